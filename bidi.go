@@ -0,0 +1,343 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// rtlLanguages are the languages written right to left. Invoices in them are
+// laid out mirrored: item names on the right, amounts on the left.
+var rtlLanguages = map[string]bool{"ar": true, "fa": true, "he": true, "ur": true, "yi": true}
+
+// rightToLeft is set while rendering an invoice in a right-to-left language.
+var rightToLeft bool
+
+// isRTLLanguage reports whether a language code (e.g. "ar" or "he-IL") is
+// written right to left.
+func isRTLLanguage(code string) bool {
+	base := strings.SplitN(strings.ReplaceAll(code, "_", "-"), "-", 2)[0]
+	return rtlLanguages[strings.ToLower(base)]
+}
+
+// visualText prepares a line of text for printing left to right: Arabic
+// letters take their joined forms, and right-to-left runs are reordered as
+// the Unicode bidirectional algorithm would. It handles the common cases
+// (mixed scripts, numbers, amounts and dates) rather than the full algorithm
+// with explicit embeddings.
+func visualText(text string, rtl bool) string {
+	runes := shapeArabic([]rune(text))
+	hasRTL := false
+	for _, r := range runes {
+		if isRTLRune(r) {
+			hasRTL = true
+			break
+		}
+	}
+	if !hasRTL && !rtl {
+		return text
+	}
+
+	levels := bidiLevels(runes, rtl)
+	for i, r := range runes {
+		if levels[i]%2 == 1 {
+			runes[i] = mirrorRune(r)
+		}
+	}
+	// rule L2: from the highest level down, reverse every run at that level or higher
+	highest := 0
+	for _, level := range levels {
+		if level > highest {
+			highest = level
+		}
+	}
+	for level := highest; level >= 1; level-- {
+		for i := 0; i < len(runes); {
+			if levels[i] < level {
+				i++
+				continue
+			}
+			j := i
+			for j < len(runes) && levels[j] >= level {
+				j++
+			}
+			for a, b := i, j-1; a < b; a, b = a+1, b-1 {
+				runes[a], runes[b] = runes[b], runes[a]
+				levels[a], levels[b] = levels[b], levels[a]
+			}
+			i = j
+		}
+	}
+	return string(runes)
+}
+
+// Bidirectional character types, simplified.
+const (
+	bidiL  = iota // left-to-right letter
+	bidiR         // right-to-left letter
+	bidiEN        // digit
+	bidiCS        // separator inside numbers: . , : / -
+	bidiET        // terminator next to numbers: currency symbols, %, #, +
+	bidiON        // other neutral: spaces and punctuation
+)
+
+func bidiType(r rune) int {
+	switch {
+	case unicode.IsDigit(r):
+		return bidiEN
+	case isRTLRune(r):
+		return bidiR
+	case r == '.' || r == ',' || r == ':' || r == '/' || r == '-':
+		return bidiCS
+	case r == '%' || r == '#' || r == '+' || unicode.Is(unicode.Sc, r):
+		return bidiET
+	case unicode.IsLetter(r) || unicode.IsMark(r):
+		return bidiL
+	}
+	return bidiON
+}
+
+// bidiLevels resolves the embedding level of every character of a paragraph.
+func bidiLevels(runes []rune, rtl bool) []int {
+	base, baseType := 0, bidiL
+	if rtl {
+		base, baseType = 1, bidiR
+	}
+	types := make([]int, len(runes))
+	for i, r := range runes {
+		types[i] = bidiType(r)
+	}
+
+	// W4: a single separator between two digits belongs to the number
+	for i := 1; i+1 < len(types); i++ {
+		if types[i] == bidiCS && types[i-1] == bidiEN && types[i+1] == bidiEN {
+			types[i] = bidiEN
+		}
+	}
+	// W5: terminators next to a number belong to it
+	for i := range types {
+		if types[i] != bidiET {
+			continue
+		}
+		j := i
+		for j < len(types) && types[j] == bidiET {
+			j++
+		}
+		if (i > 0 && types[i-1] == bidiEN) || (j < len(types) && types[j] == bidiEN) {
+			for k := i; k < j; k++ {
+				types[k] = bidiEN
+			}
+		}
+	}
+	// W6: leftover separators and terminators are neutral
+	for i, t := range types {
+		if t == bidiCS || t == bidiET {
+			types[i] = bidiON
+		}
+	}
+	// W7: numbers after left-to-right text (or at the start of an LTR paragraph) are LTR
+	strong := baseType
+	for i, t := range types {
+		switch t {
+		case bidiL, bidiR:
+			strong = t
+		case bidiEN:
+			if strong == bidiL {
+				types[i] = bidiL
+			}
+		}
+	}
+	// N1/N2: neutrals take the direction of the text around them when both sides
+	// agree (numbers count as RTL here), otherwise the paragraph direction
+	direction := func(t int) int {
+		if t == bidiEN {
+			return bidiR
+		}
+		return t
+	}
+	for i := 0; i < len(types); {
+		if types[i] != bidiON {
+			i++
+			continue
+		}
+		j := i
+		for j < len(types) && types[j] == bidiON {
+			j++
+		}
+		before, after := baseType, baseType
+		if i > 0 {
+			before = direction(types[i-1])
+		}
+		if j < len(types) {
+			after = direction(types[j])
+		}
+		resolved := baseType
+		if before == after {
+			resolved = before
+		}
+		for k := i; k < j; k++ {
+			types[k] = resolved
+		}
+		i = j
+	}
+
+	// I1/I2
+	levels := make([]int, len(types))
+	for i, t := range types {
+		switch {
+		case base == 0 && t == bidiR:
+			levels[i] = 1
+		case base == 0 && t == bidiEN:
+			levels[i] = 2
+		case base == 1 && t != bidiR:
+			levels[i] = 2
+		default:
+			levels[i] = base
+		}
+	}
+	return levels
+}
+
+// isRTLRune reports whether r is a Hebrew or Arabic letter. Arabic-Indic
+// digits are numbers, not letters.
+func isRTLRune(r rune) bool {
+	if unicode.IsDigit(r) {
+		return false
+	}
+	return (r >= 0x0590 && r <= 0x08FF) || (r >= 0xFB1D && r <= 0xFDFF) || (r >= 0xFE70 && r <= 0xFEFF)
+}
+
+var mirroredRunes = map[rune]rune{
+	'(': ')', ')': '(', '[': ']', ']': '[', '{': '}', '}': '{',
+	'<': '>', '>': '<', '«': '»', '»': '«',
+}
+
+func mirrorRune(r rune) rune {
+	if m, ok := mirroredRunes[r]; ok {
+		return m
+	}
+	return r
+}
+
+// arabicForms maps Arabic letters to their presentation forms: isolated,
+// final, initial and medial. Letters without initial and medial forms only
+// join the letter before them.
+var arabicForms = map[rune][4]rune{
+	0x0621: {0xFE80, 0, 0, 0},
+	0x0622: {0xFE81, 0xFE82, 0, 0},
+	0x0623: {0xFE83, 0xFE84, 0, 0},
+	0x0624: {0xFE85, 0xFE86, 0, 0},
+	0x0625: {0xFE87, 0xFE88, 0, 0},
+	0x0626: {0xFE89, 0xFE8A, 0xFE8B, 0xFE8C},
+	0x0627: {0xFE8D, 0xFE8E, 0, 0},
+	0x0628: {0xFE8F, 0xFE90, 0xFE91, 0xFE92},
+	0x0629: {0xFE93, 0xFE94, 0, 0},
+	0x062A: {0xFE95, 0xFE96, 0xFE97, 0xFE98},
+	0x062B: {0xFE99, 0xFE9A, 0xFE9B, 0xFE9C},
+	0x062C: {0xFE9D, 0xFE9E, 0xFE9F, 0xFEA0},
+	0x062D: {0xFEA1, 0xFEA2, 0xFEA3, 0xFEA4},
+	0x062E: {0xFEA5, 0xFEA6, 0xFEA7, 0xFEA8},
+	0x062F: {0xFEA9, 0xFEAA, 0, 0},
+	0x0630: {0xFEAB, 0xFEAC, 0, 0},
+	0x0631: {0xFEAD, 0xFEAE, 0, 0},
+	0x0632: {0xFEAF, 0xFEB0, 0, 0},
+	0x0633: {0xFEB1, 0xFEB2, 0xFEB3, 0xFEB4},
+	0x0634: {0xFEB5, 0xFEB6, 0xFEB7, 0xFEB8},
+	0x0635: {0xFEB9, 0xFEBA, 0xFEBB, 0xFEBC},
+	0x0636: {0xFEBD, 0xFEBE, 0xFEBF, 0xFEC0},
+	0x0637: {0xFEC1, 0xFEC2, 0xFEC3, 0xFEC4},
+	0x0638: {0xFEC5, 0xFEC6, 0xFEC7, 0xFEC8},
+	0x0639: {0xFEC9, 0xFECA, 0xFECB, 0xFECC},
+	0x063A: {0xFECD, 0xFECE, 0xFECF, 0xFED0},
+	0x0641: {0xFED1, 0xFED2, 0xFED3, 0xFED4},
+	0x0642: {0xFED5, 0xFED6, 0xFED7, 0xFED8},
+	0x0643: {0xFED9, 0xFEDA, 0xFEDB, 0xFEDC},
+	0x0644: {0xFEDD, 0xFEDE, 0xFEDF, 0xFEE0},
+	0x0645: {0xFEE1, 0xFEE2, 0xFEE3, 0xFEE4},
+	0x0646: {0xFEE5, 0xFEE6, 0xFEE7, 0xFEE8},
+	0x0647: {0xFEE9, 0xFEEA, 0xFEEB, 0xFEEC},
+	0x0648: {0xFEED, 0xFEEE, 0, 0},
+	0x0649: {0xFEEF, 0xFEF0, 0, 0},
+	0x064A: {0xFEF1, 0xFEF2, 0xFEF3, 0xFEF4},
+	0x067E: {0xFB56, 0xFB57, 0xFB58, 0xFB59}, // Persian peh
+	0x0686: {0xFB7A, 0xFB7B, 0xFB7C, 0xFB7D}, // tcheh
+	0x0698: {0xFB8A, 0xFB8B, 0, 0},           // jeh
+	0x06A9: {0xFB8E, 0xFB8F, 0xFB90, 0xFB91}, // keheh
+	0x06AF: {0xFB92, 0xFB93, 0xFB94, 0xFB95}, // gaf
+	0x06CC: {0xFBFC, 0xFBFD, 0xFBFE, 0xFBFF}, // Farsi yeh
+}
+
+// lamAlef maps the alef that follows a lam to the isolated and final forms of
+// their ligature.
+var lamAlef = map[rune][2]rune{
+	0x0622: {0xFEF5, 0xFEF6},
+	0x0623: {0xFEF7, 0xFEF8},
+	0x0625: {0xFEF9, 0xFEFA},
+	0x0627: {0xFEFB, 0xFEFC},
+}
+
+const tatweel = 0x0640
+
+// isArabicMark reports whether r is a vowel mark, which is transparent to joining.
+func isArabicMark(r rune) bool {
+	return (r >= 0x064B && r <= 0x065F) || r == 0x0670
+}
+
+// joinsBoth reports whether r connects to the letters on both sides.
+func joinsBoth(r rune) bool {
+	forms, ok := arabicForms[r]
+	return r == tatweel || (ok && forms[2] != 0)
+}
+
+// joinsBefore reports whether r connects to the letter before it.
+func joinsBefore(r rune) bool {
+	forms, ok := arabicForms[r]
+	return r == tatweel || (ok && forms[1] != 0)
+}
+
+// shapeArabic replaces Arabic letters, in logical order, with the presentation
+// form for their position in the word, so fonts without shaping tables still
+// print connected script.
+func shapeArabic(runes []rune) []rune {
+	// neighbor finds the closest letter in direction step, skipping vowel marks
+	neighbor := func(i, step int) rune {
+		for j := i + step; j >= 0 && j < len(runes); j += step {
+			if !isArabicMark(runes[j]) {
+				return runes[j]
+			}
+		}
+		return 0
+	}
+	out := make([]rune, 0, len(runes))
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		forms, ok := arabicForms[r]
+		if !ok {
+			out = append(out, r)
+			continue
+		}
+		joinPrev := joinsBoth(neighbor(i, -1)) && joinsBefore(r)
+		if r == 0x0644 && i+1 < len(runes) {
+			if ligature, ok := lamAlef[runes[i+1]]; ok {
+				if joinPrev {
+					out = append(out, ligature[1])
+				} else {
+					out = append(out, ligature[0])
+				}
+				i++
+				continue
+			}
+		}
+		joinNext := joinsBoth(r) && joinsBefore(neighbor(i, 1))
+		switch {
+		case joinPrev && joinNext:
+			out = append(out, forms[3])
+		case joinPrev:
+			out = append(out, forms[1])
+		case joinNext:
+			out = append(out, forms[2])
+		default:
+			out = append(out, forms[0])
+		}
+	}
+	return out
+}
@@ -0,0 +1,48 @@
+package main
+
+import "testing"
+
+func TestShapeArabic(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []rune
+	}{
+		{"isolated letter", "ب", []rune{0xFE8F}},
+		{"initial and final", "بب", []rune{0xFE91, 0xFE90}},
+		{"medial", "ببب", []rune{0xFE91, 0xFE92, 0xFE90}},
+		{"letter joining only before", "دب", []rune{0xFEA9, 0xFE8F}},
+		{"lam-alef ligature", "سلام", []rune{0xFEB3, 0xFEFC, 0xFEE1}},
+		{"vowel marks are transparent", "بَب", []rune{0xFE91, 0x064E, 0xFE90}},
+		{"word", "فاتورة", []rune{0xFED3, 0xFE8E, 0xFE97, 0xFEEE, 0xFEAD, 0xFE93}},
+		{"latin untouched", "ab", []rune("ab")},
+	}
+	for _, tt := range tests {
+		if got := shapeArabic([]rune(tt.text)); string(got) != string(tt.want) {
+			t.Errorf("%s: shapeArabic(%q) = %U, want %U", tt.name, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestVisualText(t *testing.T) {
+	tests := []struct {
+		text string
+		rtl  bool
+		want string
+	}{
+		{"Invoice 12", false, "Invoice 12"},
+		{"Invoice 12", true, "Invoice 12"},
+		{"שלום", true, "םולש"},
+		{"שלום 123", true, "123 םולש"},
+		{"abc שלום def", false, "abc םולש def"},
+		{"סכום 1,234.56", true, "1,234.56 םוכס"},
+		{"(שלום)", true, "(םולש)"},
+		{"Total 100 ש", true, "ש Total 100"},
+		{"فاتورة 2026-01-15", true, "2026-01-15 " + string([]rune{0xFE93, 0xFEAD, 0xFEEE, 0xFE97, 0xFE8E, 0xFED3})},
+	}
+	for _, tt := range tests {
+		if got := visualText(tt.text, tt.rtl); got != tt.want {
+			t.Errorf("visualText(%q, %v) = %q, want %q", tt.text, tt.rtl, got, tt.want)
+		}
+	}
+}
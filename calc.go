@@ -0,0 +1,347 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// moneyPlaces is the number of decimals every money amount is rounded to.
+const moneyPlaces = 2
+
+// RoundingScope selects where tax is rounded to whole cents.
+type RoundingScope string
+
+const (
+	// ScopeLine rounds the tax of every line and sums the rounded values.
+	ScopeLine RoundingScope = "line"
+	// ScopeDocument sums the line nets and rounds the tax once for the whole invoice.
+	ScopeDocument RoundingScope = "document"
+)
+
+// parseRoundingScope validates a rounding scope, defaulting to per line.
+func parseRoundingScope(s string) (RoundingScope, error) {
+	switch RoundingScope(s) {
+	case "", ScopeLine:
+		return ScopeLine, nil
+	case ScopeDocument:
+		return ScopeDocument, nil
+	}
+	return "", fmt.Errorf("unknown rounding scope %q (use %s or %s)", s, ScopeLine, ScopeDocument)
+}
+
+// DiscountOrder says whether discounts reduce the taxable net amount (before
+// tax) or are taken off the gross amount (after tax).
+type DiscountOrder string
+
+const (
+	DiscountBeforeTax DiscountOrder = "beforeTax"
+	DiscountAfterTax  DiscountOrder = "afterTax"
+)
+
+// parseDiscountOrder validates a discount order, defaulting to before tax.
+func parseDiscountOrder(s string) (DiscountOrder, error) {
+	switch DiscountOrder(s) {
+	case "", DiscountBeforeTax:
+		return DiscountBeforeTax, nil
+	case DiscountAfterTax:
+		return DiscountAfterTax, nil
+	}
+	return "", fmt.Errorf("unknown discount order %q (use %s or %s)", s, DiscountBeforeTax, DiscountAfterTax)
+}
+
+// LineTotals holds the computed, rounded amounts of one line item. TaxRate is
+// the effective rate of all taxes applied to the line. When the line has a
+// discount, Original is the undiscounted amount of the column it applies to:
+// the net amount before tax, or the gross amount after tax. Before tax,
+// Discount includes the line's share of the document discount.
+type LineTotals struct {
+	Item     LineItem
+	TaxRate  Decimal
+	Original Decimal
+	Discount Decimal
+	Net      Decimal
+	Tax      Decimal
+	Gross    Decimal
+}
+
+// TaxGroup sums the lines that share a tax, rate and category, as printed in
+// the tax summary table. Net is the taxable base, which for a compound tax
+// includes the taxes applied before it.
+type TaxGroup struct {
+	Name     string
+	Rate     Decimal
+	Category string
+	Compound bool
+	Net      Decimal
+	Tax      Decimal
+	Gross    Decimal
+
+	// order is the position of the tax in the invoice tax list, exact holds
+	// the unrounded tax for document-level rounding and lines the indexes of
+	// the lines taxed in the group.
+	order int
+	exact Decimal
+	lines []int
+}
+
+// TaxTotal is the total amount of one named tax across all rates.
+type TaxTotal struct {
+	Name   string
+	Amount Decimal
+}
+
+// Totals holds every computed amount of the invoice. Lines only contains the
+// items that are printed (zero quantities are skipped). Net is the sum of the
+// line nets before the document discount; Discount covers the document
+// discount and line discounts taken off after tax.
+type Totals struct {
+	Lines     []LineTotals
+	TaxGroups []TaxGroup
+	TaxTotals []TaxTotal
+	Net       Decimal
+	Tax       Decimal
+	Discount  Decimal
+	Gross     Decimal
+	// Withholding is the positive amount withheld; it is deducted from Due.
+	Withholding Decimal
+	Paid        Decimal
+	Due         Decimal
+}
+
+// computeTotals calculates all invoice amounts with exact decimal arithmetic.
+// Line nets are always rounded per line so the net total equals the sum of the
+// printed line values; the rounding scope decides whether tax is rounded per
+// line or once per tax group, in which case the rounding difference is added
+// to the group's largest line so the printed lines still add up.
+func computeTotals(inv *Invoice) (Totals, error) {
+	var totals Totals
+	mode, err := parseRoundingMode(inv.Rounding)
+	if err != nil {
+		return totals, err
+	}
+	scope, err := parseRoundingScope(inv.RoundingScope)
+	if err != nil {
+		return totals, err
+	}
+	treatment, err := checkTaxTreatment(inv)
+	if err != nil {
+		return totals, err
+	}
+	discountOrder, err := parseDiscountOrder(inv.DiscountOrder)
+	if err != nil {
+		return totals, err
+	}
+	round := func(d Decimal) Decimal { return d.Round(moneyPlaces, mode) }
+	// discountOf is a percentage of base plus a fixed amount.
+	discountOf := func(base Decimal, rate, amount float64) Decimal {
+		return round(base.Mul(decimalFromFloat(rate))).Add(round(decimalFromFloat(amount)))
+	}
+
+	groups := map[string]*TaxGroup{}
+	order := map[string]int{}
+	for i, tax := range invoiceTaxes(inv) {
+		if _, ok := order[tax.Name]; !ok {
+			order[tax.Name] = i
+		}
+	}
+	// Line nets first; discounts before tax lower the net that is taxed.
+	for _, item := range inv.Items {
+		// If quantity is explicitly set to 0, skip this item entirely.
+		if item.Quantity == 0 {
+			continue
+		}
+		original := round(decimalFromFloat(item.Quantity).Mul(decimalFromFloat(item.UnitPrice)))
+		line := LineTotals{Item: item, Original: original, Net: original}
+		if discountOrder == DiscountBeforeTax {
+			line.Discount = discountOf(original, item.Discount, item.DiscountAmount)
+			line.Net = original.Sub(line.Discount)
+		}
+		// credit and deduction lines are negative; only a discount larger
+		// than the line itself is an error
+		if !line.Discount.IsZero() && line.Discount.Cmp(original.Abs()) > 0 {
+			return totals, fmt.Errorf("item %q: discount exceeds the line amount", item.Description)
+		}
+		totals.Lines = append(totals.Lines, line)
+		totals.Net = totals.Net.Add(line.Net)
+	}
+
+	// The document discount is a percentage of the net total plus a fixed
+	// amount, in both discount orders. Before tax it is spread over the lines in proportion to their
+	// net amounts, so every tax rate is charged on its discounted base.
+	documentDiscount := discountOf(totals.Net, inv.Discount, inv.DiscountAmount)
+	if discountOrder == DiscountBeforeTax && !documentDiscount.IsZero() {
+		if documentDiscount.Cmp(totals.Net.Abs()) > 0 {
+			return totals, fmt.Errorf("discount exceeds the invoice net amount")
+		}
+		allocateDiscount(totals.Lines, totals.Net, documentDiscount, round)
+	}
+
+	for i := range totals.Lines {
+		line := &totals.Lines[i]
+		item := line.Item
+		if item.TaxRate != nil && len(inv.Taxes) > 0 {
+			return totals, fmt.Errorf("item %q: taxRate can't be combined with a taxes list, where its tax would have no name", item.Description)
+		}
+		taxes := itemTaxes(inv, item)
+		// Categories distinguish rates; without tax they would only split the summary.
+		category := item.TaxCategory
+		if treatment != TreatmentStandard {
+			category = ""
+		}
+		net := line.Net
+
+		// Each tax is calculated on the net amount, or for a compound tax on the
+		// net amount plus the taxes before it on this line.
+		var lineTax, exactLineTax Decimal
+		for _, tax := range taxes {
+			rate := decimalFromFloat(tax.Rate)
+			base, exactBase := net, net
+			if tax.Compound {
+				base, exactBase = net.Add(lineTax), net.Add(exactLineTax)
+			}
+			amount := round(base.Mul(rate))
+			exact := exactBase.Mul(rate)
+			lineTax = lineTax.Add(amount)
+			exactLineTax = exactLineTax.Add(exact)
+
+			key := tax.Name + "/" + rate.rat().RatString() + "/" + category
+			if tax.Compound {
+				key += "/compound"
+			}
+			group, ok := groups[key]
+			if !ok {
+				group = &TaxGroup{Name: tax.Name, Rate: rate, Category: category, Compound: tax.Compound, order: len(order)}
+				if o, known := order[tax.Name]; known {
+					group.order = o
+				}
+				groups[key] = group
+			}
+			if scope == ScopeDocument {
+				group.Net = group.Net.Add(exactBase)
+			} else {
+				group.Net = group.Net.Add(base)
+			}
+			group.Tax = group.Tax.Add(amount)
+			group.exact = group.exact.Add(exact)
+			group.lines = append(group.lines, i)
+		}
+		line.TaxRate = effectiveTaxRate(taxes)
+		line.Tax = lineTax
+		line.Gross = net.Add(lineTax)
+	}
+
+	for _, group := range groups {
+		if scope == ScopeDocument {
+			group.Net = round(group.Net)
+			// the line taxes are rounded one by one, so the difference to the
+			// group's rounded tax goes to its largest line, as printed rows
+			// must add up to the totals
+			rounded := round(group.exact)
+			allocateTaxResidual(totals.Lines, group.lines, rounded.Sub(group.Tax))
+			group.Tax = rounded
+		}
+		group.Gross = group.Net.Add(group.Tax)
+		totals.TaxGroups = append(totals.TaxGroups, *group)
+		totals.Tax = totals.Tax.Add(group.Tax)
+	}
+
+	for i := range totals.Lines {
+		line := &totals.Lines[i]
+		item := line.Item
+		if discountOrder == DiscountAfterTax {
+			// as for the document discount, the percentage is of the net
+			// amount and the discount is taken off the gross amount
+			line.Original = line.Gross
+			line.Discount = discountOf(line.Net, item.Discount, item.DiscountAmount)
+			line.Gross = line.Gross.Sub(line.Discount)
+			totals.Discount = totals.Discount.Add(line.Discount)
+			if !line.Discount.IsZero() && line.Discount.Cmp(line.Original.Abs()) > 0 {
+				return totals, fmt.Errorf("item %q: discount exceeds the line amount", item.Description)
+			}
+		}
+	}
+	// Taxes in the order they were defined, then highest rate first and by
+	// category, so the summary reads like a VAT table.
+	sort.Slice(totals.TaxGroups, func(i, j int) bool {
+		a, b := totals.TaxGroups[i], totals.TaxGroups[j]
+		if a.order != b.order {
+			return a.order < b.order
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if c := a.Rate.Cmp(b.Rate); c != 0 {
+			return c > 0
+		}
+		return a.Category < b.Category
+	})
+	for _, group := range totals.TaxGroups {
+		n := len(totals.TaxTotals)
+		if n > 0 && totals.TaxTotals[n-1].Name == group.Name {
+			totals.TaxTotals[n-1].Amount = totals.TaxTotals[n-1].Amount.Add(group.Tax)
+			continue
+		}
+		totals.TaxTotals = append(totals.TaxTotals, TaxTotal{Name: group.Name, Amount: group.Tax})
+	}
+
+	// After tax the discount is taken off the gross amount, so only that
+	// limits it.
+	if discountOrder == DiscountAfterTax && !documentDiscount.IsZero() {
+		gross := totals.Net.Add(totals.Tax).Sub(totals.Discount)
+		if documentDiscount.Cmp(gross.Abs()) > 0 {
+			return totals, fmt.Errorf("discount exceeds the invoice gross amount")
+		}
+	}
+
+	// Either way the gross is net − discount + tax; the order only changes
+	// which base the tax was calculated on.
+	totals.Discount = totals.Discount.Add(documentDiscount)
+	totals.Gross = totals.Net.Add(totals.Tax).Sub(totals.Discount)
+	// Withholding is a share of the discounted net amount kept back by the
+	// buyer, so it lowers what is due without changing the invoice gross. Net
+	// less Discount is that amount in both discount orders.
+	taxableNet := totals.Net.Sub(totals.Discount)
+	totals.Withholding = round(taxableNet.Mul(decimalFromFloat(inv.Withholding)))
+	totals.Paid = round(decimalFromFloat(inv.Paid))
+	totals.Due = totals.Gross.Sub(totals.Withholding).Sub(totals.Paid)
+	return totals, nil
+}
+
+// allocateDiscount spreads a document discount over the lines in proportion to
+// their net amounts. Each share is rounded to cents and the rounding residual
+// goes to the largest line, so the shares always add up to the discount.
+func allocateDiscount(lines []LineTotals, total, discount Decimal, round func(Decimal) Decimal) {
+	if total.IsZero() {
+		return
+	}
+	largest := 0
+	var allocated Decimal
+	for i := range lines {
+		share := round(discount.Mul(lines[i].Net).Quo(total))
+		lines[i].Discount = lines[i].Discount.Add(share)
+		lines[i].Net = lines[i].Net.Sub(share)
+		allocated = allocated.Add(share)
+		if lines[i].Net.Cmp(lines[largest].Net) > 0 {
+			largest = i
+		}
+	}
+	residual := discount.Sub(allocated)
+	lines[largest].Discount = lines[largest].Discount.Add(residual)
+	lines[largest].Net = lines[largest].Net.Sub(residual)
+}
+
+// allocateTaxResidual adds the difference between a tax group's rounded tax
+// and the sum of its rounded line taxes to the group's largest line.
+func allocateTaxResidual(lines []LineTotals, group []int, residual Decimal) {
+	if residual.IsZero() || len(group) == 0 {
+		return
+	}
+	largest := group[0]
+	for _, i := range group {
+		if lines[i].Net.Abs().Cmp(lines[largest].Net.Abs()) > 0 {
+			largest = i
+		}
+	}
+	lines[largest].Tax = lines[largest].Tax.Add(residual)
+	lines[largest].Gross = lines[largest].Gross.Add(residual)
+}
@@ -0,0 +1,233 @@
+package main
+
+import "testing"
+
+// lineSums adds up the printed net and gross columns.
+func lineSums(totals Totals) (net, gross Decimal) {
+	for _, line := range totals.Lines {
+		net = net.Add(line.Net)
+		gross = gross.Add(line.Gross)
+	}
+	return net, gross
+}
+
+func TestDocumentRoundingLinesAddUp(t *testing.T) {
+	inv := Invoice{
+		Tax:           0.05,
+		RoundingScope: string(ScopeDocument),
+		Items: []LineItem{
+			{Description: "a", Quantity: 1, UnitPrice: 0.10},
+			{Description: "b", Quantity: 1, UnitPrice: 0.10},
+			{Description: "c", Quantity: 1, UnitPrice: 0.10},
+		},
+	}
+	totals, err := computeTotals(&inv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := totals.Gross.StringFixed(2); got != "0.32" {
+		t.Errorf("gross = %s, want 0.32", got)
+	}
+	if _, gross := lineSums(totals); gross.Cmp(totals.Gross) != 0 {
+		t.Errorf("line gross sums to %s, totals say %s", gross.StringFixed(2), totals.Gross.StringFixed(2))
+	}
+}
+
+func TestNegativeLinesWithoutDiscount(t *testing.T) {
+	for _, order := range []DiscountOrder{DiscountBeforeTax, DiscountAfterTax} {
+		inv := Invoice{
+			Tax:           0.23,
+			DiscountOrder: string(order),
+			Items: []LineItem{
+				{Description: "service", Quantity: 1, UnitPrice: 100},
+				{Description: "credit", Quantity: -1, UnitPrice: 20},
+				{Description: "deposit", Quantity: 1, UnitPrice: -30},
+			},
+		}
+		totals, err := computeTotals(&inv)
+		if err != nil {
+			t.Fatalf("%s: %v", order, err)
+		}
+		if got := totals.Net.StringFixed(2); got != "50.00" {
+			t.Errorf("%s: net = %s, want 50.00", order, got)
+		}
+	}
+}
+
+func TestLineDiscountOvershoot(t *testing.T) {
+	for _, order := range []DiscountOrder{DiscountBeforeTax, DiscountAfterTax} {
+		inv := Invoice{
+			DiscountOrder: string(order),
+			Items:         []LineItem{{Description: "a", Quantity: 1, UnitPrice: 10, DiscountAmount: 15}},
+		}
+		if _, err := computeTotals(&inv); err == nil {
+			t.Errorf("%s: a discount larger than the line was accepted", order)
+		}
+	}
+}
+
+func TestAfterTaxDiscountBase(t *testing.T) {
+	line := Invoice{
+		Tax:           0.23,
+		DiscountOrder: string(DiscountAfterTax),
+		Items:         []LineItem{{Description: "a", Quantity: 1, UnitPrice: 100, Discount: 0.1}},
+	}
+	document := Invoice{
+		Tax:           0.23,
+		DiscountOrder: string(DiscountAfterTax),
+		Discount:      0.1,
+		Items:         []LineItem{{Description: "a", Quantity: 1, UnitPrice: 100}},
+	}
+	for name, inv := range map[string]*Invoice{"line": &line, "document": &document} {
+		totals, err := computeTotals(inv)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if got := totals.Discount.StringFixed(2); got != "10.00" {
+			t.Errorf("%s discount = %s, want 10.00", name, got)
+		}
+		if got := totals.Gross.StringFixed(2); got != "113.00" {
+			t.Errorf("%s gross = %s, want 113.00", name, got)
+		}
+	}
+}
+
+func TestDocumentDiscountLimit(t *testing.T) {
+	tests := []struct {
+		name    string
+		inv     Invoice
+		wantErr bool
+	}{
+		{"all-credit invoice", Invoice{Tax: 0.23, Items: []LineItem{{Description: "credit", Quantity: -1, UnitPrice: 100}}}, false},
+		{"after tax below gross", Invoice{Tax: 0.23, DiscountOrder: string(DiscountAfterTax), DiscountAmount: 110,
+			Items: []LineItem{{Description: "a", Quantity: 1, UnitPrice: 100}}}, false},
+		{"after tax above gross", Invoice{Tax: 0.23, DiscountOrder: string(DiscountAfterTax), DiscountAmount: 124,
+			Items: []LineItem{{Description: "a", Quantity: 1, UnitPrice: 100}}}, true},
+		{"before tax above net", Invoice{Tax: 0.23, DiscountAmount: 110,
+			Items: []LineItem{{Description: "a", Quantity: 1, UnitPrice: 100}}}, true},
+	}
+	for _, tt := range tests {
+		_, err := computeTotals(&tt.inv)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestStackedTaxes(t *testing.T) {
+	type taxTotal struct{ name, amount string }
+	tests := []struct {
+		name  string
+		taxes []Tax
+		items []LineItem
+		want  []taxTotal
+		gross string
+	}{
+		{
+			name:  "simple GST and PST",
+			taxes: []Tax{{Name: "GST", Rate: 0.05}, {Name: "PST", Rate: 0.07}},
+			items: []LineItem{{Description: "a", Quantity: 1, UnitPrice: 100}},
+			want:  []taxTotal{{"GST", "5.00"}, {"PST", "7.00"}},
+			gross: "112.00",
+		},
+		{
+			name:  "compound QST",
+			taxes: []Tax{{Name: "GST", Rate: 0.05}, {Name: "QST", Rate: 0.09975, Compound: true}},
+			items: []LineItem{{Description: "a", Quantity: 1, UnitPrice: 100}},
+			want:  []taxTotal{{"GST", "5.00"}, {"QST", "10.47"}},
+			gross: "115.47",
+		},
+		{
+			name:  "compound QST over two lines",
+			taxes: []Tax{{Name: "GST", Rate: 0.05}, {Name: "QST", Rate: 0.09975, Compound: true}},
+			items: []LineItem{
+				{Description: "a", Quantity: 1, UnitPrice: 100},
+				{Description: "b", Quantity: 2, UnitPrice: 10.5},
+			},
+			want:  []taxTotal{{"GST", "6.05"}, {"QST", "12.67"}},
+			gross: "139.72",
+		},
+		{
+			name:  "single tax in the list",
+			taxes: []Tax{{Name: "VAT", Rate: 0.2}},
+			items: []LineItem{{Description: "a", Quantity: 3, UnitPrice: 10}},
+			want:  []taxTotal{{"VAT", "6.00"}},
+			gross: "36.00",
+		},
+	}
+	for _, tt := range tests {
+		inv := Invoice{Taxes: tt.taxes, Items: tt.items}
+		totals, err := computeTotals(&inv)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		got := []taxTotal{}
+		for _, tax := range totals.TaxTotals {
+			got = append(got, taxTotal{tax.Name, tax.Amount.StringFixed(2)})
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: tax totals = %v, want %v", tt.name, got, tt.want)
+		} else {
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("%s: tax totals = %v, want %v", tt.name, got, tt.want)
+					break
+				}
+			}
+		}
+		if g := totals.Gross.StringFixed(2); g != tt.gross {
+			t.Errorf("%s: gross = %s, want %s", tt.name, g, tt.gross)
+		}
+		if _, gross := lineSums(totals); gross.Cmp(totals.Gross) != 0 {
+			t.Errorf("%s: line gross sums to %s, totals say %s", tt.name, gross.StringFixed(2), totals.Gross.StringFixed(2))
+		}
+	}
+}
+
+func TestItemTaxRateWithTaxesList(t *testing.T) {
+	zero := 0.0
+	inv := Invoice{
+		Taxes: []Tax{{Name: "GST", Rate: 0.05}, {Name: "QST", Rate: 0.09975, Compound: true}},
+		Items: []LineItem{
+			{Description: "a", Quantity: 1, UnitPrice: 100},
+			{Description: "groceries", Quantity: 1, UnitPrice: 20, TaxRate: &zero},
+		},
+	}
+	if _, err := computeTotals(&inv); err == nil {
+		t.Error("an item taxRate alongside a taxes list was accepted")
+	}
+}
+
+func TestWithholding(t *testing.T) {
+	tests := []struct {
+		name                   string
+		order                  DiscountOrder
+		discount, lineDiscount float64
+		withholding, due       string
+	}{
+		{"no discount", DiscountBeforeTax, 0, 0, "150.00", "1080.00"},
+		{"document discount before tax", DiscountBeforeTax, 0.1, 0, "135.00", "972.00"},
+		{"document discount after tax", DiscountAfterTax, 0.1, 0, "135.00", "995.00"},
+		{"line discount before tax", DiscountBeforeTax, 0, 0.2, "120.00", "864.00"},
+		{"line discount after tax", DiscountAfterTax, 0, 0.2, "120.00", "910.00"},
+	}
+	for _, tt := range tests {
+		inv := Invoice{
+			Tax:           0.23,
+			Withholding:   0.15,
+			Discount:      tt.discount,
+			DiscountOrder: string(tt.order),
+			Items:         []LineItem{{Description: "consulting", Quantity: 1, UnitPrice: 1000, Discount: tt.lineDiscount}},
+		}
+		totals, err := computeTotals(&inv)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := totals.Withholding.StringFixed(2); got != tt.withholding {
+			t.Errorf("%s: withholding = %s, want %s", tt.name, got, tt.withholding)
+		}
+		if got := totals.Due.StringFixed(2); got != tt.due {
+			t.Errorf("%s: due = %s, want %s", tt.name, got, tt.due)
+		}
+	}
+}
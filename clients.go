@@ -0,0 +1,371 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// Client is an entry of the address book: the buyer's details and the
+// invoice settings used for them.
+type Client struct {
+	Party Party `json:"party" yaml:"party"`
+	// Currency, Lang and TaxTreatment replace the invoice defaults, and
+	// PaymentTerms sets the due date that many days after the issue date.
+	Currency     string `json:"currency,omitempty" yaml:"currency,omitempty"`
+	Lang         string `json:"lang,omitempty" yaml:"lang,omitempty"`
+	PaymentTerms int    `json:"paymentTerms,omitempty" yaml:"paymentTerms,omitempty"`
+	TaxTreatment string `json:"taxTreatment,omitempty" yaml:"taxTreatment,omitempty"`
+}
+
+// clientID is the --client flag of generate.
+var clientID string
+
+// idPattern is the form of client IDs and profile names.
+var idPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+
+// clientsPath returns the address book file in the config directory.
+func clientsPath() (string, error) {
+	dir := configDir()
+	if dir == "" {
+		return "", fmt.Errorf("no config directory on this system for the client address book")
+	}
+	return filepath.Join(dir, "clients.yaml"), nil
+}
+
+// loadClients reads the address book. A missing file is an empty book.
+func loadClients() (map[string]Client, error) {
+	clients := map[string]Client{}
+	path, err := clientsPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return clients, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read client address book %s: %w", path, err)
+	}
+	if err := yaml.Unmarshal(data, &clients); err != nil {
+		return nil, fmt.Errorf("unable to parse client address book %s: %w", path, err)
+	}
+	return clients, nil
+}
+
+// saveClients writes the address book.
+func saveClients(clients map[string]Client) error {
+	path, err := clientsPath()
+	if err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(clients)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("unable to create config directory %s: %w", filepath.Dir(path), err)
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return fmt.Errorf("unable to write client address book %s: %w", path, err)
+	}
+	return nil
+}
+
+// lockClients locks the address book while a command changes it, so that
+// two commands at the same time don't lose each other's clients.
+func lockClients() (func(), error) {
+	path, err := clientsPath()
+	if err != nil {
+		return nil, err
+	}
+	return lockFile(path)
+}
+
+// findClient returns a client of the address book by its ID.
+func findClient(id string) (Client, error) {
+	clients, err := loadClients()
+	if err != nil {
+		return Client{}, err
+	}
+	client, ok := clients[strings.ToLower(id)]
+	if !ok {
+		return Client{}, fmt.Errorf("unknown client %q (see invoice client list)", id)
+	}
+	return client, nil
+}
+
+// applyClient fills in the buyer and the client's invoice settings. Settings
+// given as flags are kept; those in the imported file are applied later and
+// win as well.
+func applyClient(inv *Invoice, id string, flags *pflag.FlagSet) error {
+	client, err := findClient(id)
+	if err != nil {
+		return err
+	}
+	if !flags.Changed("to") {
+		inv.To = client.Party
+	}
+	for _, setting := range []struct {
+		flag  string
+		value string
+		field *string
+	}{
+		{"currency", client.Currency, &inv.Currency},
+		{"lang", client.Lang, &inv.Lang},
+		{"taxTreatment", client.TaxTreatment, &inv.TaxTreatment},
+	} {
+		if setting.value != "" && !flags.Changed(setting.flag) {
+			*setting.field = setting.value
+		}
+	}
+	if client.PaymentTerms > 0 && !flags.Changed("paymentTerms") {
+		inv.PaymentTerms = client.PaymentTerms
+	}
+	return nil
+}
+
+var clientCmd = &cobra.Command{
+	Use:   "client",
+	Short: "Manage the client address book",
+	Long: `Manage the client address book. A client's details fill in the buyer of an
+invoice generated with --client, along with its currency, language, payment
+terms and tax treatment.`,
+}
+
+var clientAddCmd = &cobra.Command{
+	Use:   "add <id>",
+	Short: "Add a client",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id := strings.ToLower(args[0])
+		if !idPattern.MatchString(id) {
+			return fmt.Errorf("invalid client ID %q (use letters, digits, '.', '_' and '-')", args[0])
+		}
+		release, err := lockClients()
+		if err != nil {
+			return err
+		}
+		defer release()
+		clients, err := loadClients()
+		if err != nil {
+			return err
+		}
+		if _, ok := clients[id]; ok {
+			return fmt.Errorf("client %s already exists (use invoice client edit)", id)
+		}
+		var client Client
+		if err := applyClientFlags(&client, cmd.Flags()); err != nil {
+			return err
+		}
+		if client.Party.Name == "" {
+			return fmt.Errorf("a client needs a --name")
+		}
+		if err := checkClient(id, &client); err != nil {
+			return err
+		}
+		clients[id] = client
+		if err := saveClients(clients); err != nil {
+			return err
+		}
+		fmt.Printf("Added client %s\n", id)
+		return nil
+	},
+}
+
+var clientEditCmd = &cobra.Command{
+	Use:   "edit <id>",
+	Short: "Change the details of a client",
+	Long:  `Change the details of a client. Only the details given as flags change.`,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id := strings.ToLower(args[0])
+		release, err := lockClients()
+		if err != nil {
+			return err
+		}
+		defer release()
+		clients, err := loadClients()
+		if err != nil {
+			return err
+		}
+		client, ok := clients[id]
+		if !ok {
+			return fmt.Errorf("unknown client %q (see invoice client list)", args[0])
+		}
+		if err := applyClientFlags(&client, cmd.Flags()); err != nil {
+			return err
+		}
+		if err := checkClient(id, &client); err != nil {
+			return err
+		}
+		clients[id] = client
+		if err := saveClients(clients); err != nil {
+			return err
+		}
+		fmt.Printf("Updated client %s\n", id)
+		return nil
+	},
+}
+
+var clientListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the clients",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		clients, err := loadClients()
+		if err != nil {
+			return err
+		}
+		ids := []string{}
+		for id := range clients {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tCOUNTRY\tVAT ID")
+		for _, id := range ids {
+			p := clients[id].Party
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", id, p.Name, p.Country, p.VatId)
+		}
+		return w.Flush()
+	},
+}
+
+var clientShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Show the details of a client",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := findClient(args[0])
+		if err != nil {
+			return err
+		}
+		data, err := yaml.Marshal(client)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(data))
+		return nil
+	},
+}
+
+var clientRemoveCmd = &cobra.Command{
+	Use:   "remove <id>",
+	Short: "Remove a client",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id := strings.ToLower(args[0])
+		release, err := lockClients()
+		if err != nil {
+			return err
+		}
+		defer release()
+		clients, err := loadClients()
+		if err != nil {
+			return err
+		}
+		if _, ok := clients[id]; !ok {
+			return fmt.Errorf("unknown client %q (see invoice client list)", args[0])
+		}
+		delete(clients, id)
+		if err := saveClients(clients); err != nil {
+			return err
+		}
+		fmt.Printf("Removed client %s\n", id)
+		return nil
+	},
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{clientAddCmd, clientEditCmd} {
+		cmd.Flags().String("name", "", "Name")
+		cmd.Flags().StringArray("address", nil, "Address line (repeat for more lines)")
+		cmd.Flags().String("postalCode", "", "Postal code")
+		cmd.Flags().String("city", "", "City")
+		cmd.Flags().String("region", "", "State, province or region")
+		cmd.Flags().String("country", "", "Country code (ISO 3166, e.g. PL)")
+		cmd.Flags().String("vatId", "", "VAT ID")
+		cmd.Flags().StringArray("taxId", nil, "Further tax ID as TYPE=VALUE, e.g. EORI=GB123 (repeat for more)")
+		cmd.Flags().String("registrationNumber", "", "Company registration number")
+		cmd.Flags().String("email", "", "Email")
+		cmd.Flags().String("phone", "", "Phone")
+		cmd.Flags().String("currency", "", "Currency of the client's invoices")
+		cmd.Flags().String("lang", "", "Language of the client's invoices")
+		cmd.Flags().Int("paymentTerms", 0, "Days from issue date to due date")
+		cmd.Flags().String("taxTreatment", "", "Tax treatment (standard, reverse-charge, exempt, outside-scope)")
+	}
+	clientCmd.AddCommand(clientAddCmd, clientEditCmd, clientListCmd, clientShowCmd, clientRemoveCmd)
+}
+
+// applyClientFlags copies the flags given to client add or edit into a client.
+func applyClientFlags(client *Client, flags *pflag.FlagSet) error {
+	fields := map[string]*string{
+		"name":               &client.Party.Name,
+		"postalCode":         &client.Party.PostalCode,
+		"city":               &client.Party.City,
+		"region":             &client.Party.Region,
+		"country":            &client.Party.Country,
+		"vatId":              &client.Party.VatId,
+		"registrationNumber": &client.Party.RegistrationNumber,
+		"email":              &client.Party.Email,
+		"phone":              &client.Party.Phone,
+		"currency":           &client.Currency,
+		"lang":               &client.Lang,
+		"taxTreatment":       &client.TaxTreatment,
+	}
+	for name, field := range fields {
+		if flags.Changed(name) {
+			*field, _ = flags.GetString(name)
+		}
+	}
+	if flags.Changed("address") {
+		client.Party.Address, _ = flags.GetStringArray("address")
+	}
+	if flags.Changed("taxId") {
+		values, _ := flags.GetStringArray("taxId")
+		client.Party.TaxIds = nil
+		for _, value := range values {
+			kind, id, ok := strings.Cut(value, "=")
+			if !ok {
+				return fmt.Errorf("tax ID %q is not in TYPE=VALUE form", value)
+			}
+			client.Party.TaxIds = append(client.Party.TaxIds, TaxId{Type: kind, Value: id})
+		}
+	}
+	if flags.Changed("paymentTerms") {
+		client.PaymentTerms, _ = flags.GetInt("paymentTerms")
+	}
+	return nil
+}
+
+// checkClient validates a client before it is saved.
+func checkClient(id string, client *Client) error {
+	if err := checkParty("client "+id, &client.Party); err != nil {
+		return err
+	}
+	client.Currency = strings.ToUpper(client.Currency)
+	if client.Lang != "" {
+		client.Lang = canonicalLangTag(client.Lang)
+	}
+	if client.PaymentTerms < 0 {
+		return fmt.Errorf("client %s: payment terms can't be negative", id)
+	}
+	switch TaxTreatment(client.TaxTreatment) {
+	case "", TreatmentStandard, TreatmentReverseCharge, TreatmentExempt, TreatmentOutsideScope:
+	default:
+		return fmt.Errorf("client %s: unknown tax treatment %q (use %s, %s, %s or %s)", id, client.TaxTreatment,
+			TreatmentStandard, TreatmentReverseCharge, TreatmentExempt, TreatmentOutsideScope)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+)
+
+// Decimal is an exact decimal number used for all money arithmetic, so that
+// sums and products never pick up binary floating point error. The zero value
+// is 0. Decimals are immutable: every operation returns a new value.
+type Decimal struct {
+	r *big.Rat
+}
+
+// RoundingMode selects how amounts are rounded to whole cents.
+type RoundingMode string
+
+const (
+	// RoundHalfUp rounds halves away from zero (commercial rounding).
+	RoundHalfUp RoundingMode = "half-up"
+	// RoundHalfEven rounds halves to the nearest even digit (banker's rounding).
+	RoundHalfEven RoundingMode = "half-even"
+)
+
+// parseRoundingMode validates a rounding mode, defaulting to half-up.
+func parseRoundingMode(s string) (RoundingMode, error) {
+	switch RoundingMode(s) {
+	case "", RoundHalfUp:
+		return RoundHalfUp, nil
+	case RoundHalfEven:
+		return RoundHalfEven, nil
+	}
+	return "", fmt.Errorf("unknown rounding mode %q (use %s or %s)", s, RoundHalfUp, RoundHalfEven)
+}
+
+// decimalFromFloat converts a float read from JSON/YAML/CLI into the decimal it
+// was written as, e.g. 0.1 becomes exactly 1/10.
+func decimalFromFloat(f float64) Decimal {
+	r, ok := new(big.Rat).SetString(strconv.FormatFloat(f, 'f', -1, 64))
+	if !ok {
+		return Decimal{}
+	}
+	return Decimal{r: r}
+}
+
+// decimalFromInt returns n as a Decimal.
+func decimalFromInt(n int64) Decimal {
+	return Decimal{r: new(big.Rat).SetInt64(n)}
+}
+
+func (d Decimal) rat() *big.Rat {
+	if d.r == nil {
+		return new(big.Rat)
+	}
+	return d.r
+}
+
+func (d Decimal) Add(o Decimal) Decimal {
+	return Decimal{r: new(big.Rat).Add(d.rat(), o.rat())}
+}
+
+func (d Decimal) Sub(o Decimal) Decimal {
+	return Decimal{r: new(big.Rat).Sub(d.rat(), o.rat())}
+}
+
+func (d Decimal) Mul(o Decimal) Decimal {
+	return Decimal{r: new(big.Rat).Mul(d.rat(), o.rat())}
+}
+
+// Quo returns d / o; o must not be zero.
+func (d Decimal) Quo(o Decimal) Decimal {
+	return Decimal{r: new(big.Rat).Quo(d.rat(), o.rat())}
+}
+
+func (d Decimal) Neg() Decimal {
+	return Decimal{r: new(big.Rat).Neg(d.rat())}
+}
+
+func (d Decimal) Abs() Decimal {
+	return Decimal{r: new(big.Rat).Abs(d.rat())}
+}
+
+// Sign returns -1, 0 or +1.
+func (d Decimal) Sign() int {
+	return d.rat().Sign()
+}
+
+func (d Decimal) IsZero() bool {
+	return d.Sign() == 0
+}
+
+// Cmp compares d and o, returning -1, 0 or +1.
+func (d Decimal) Cmp(o Decimal) int {
+	return d.rat().Cmp(o.rat())
+}
+
+// Round rounds d to the given number of decimal places using mode.
+func (d Decimal) Round(places int, mode RoundingMode) Decimal {
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(places)), nil)
+	scaled := new(big.Rat).Mul(d.rat(), new(big.Rat).SetInt(scale))
+
+	// Split |scaled| into an integer quotient and a remainder over denom.
+	num := new(big.Int).Abs(scaled.Num())
+	denom := scaled.Denom()
+	quo, rem := new(big.Int).QuoRem(num, denom, new(big.Int))
+
+	// Compare twice the remainder against the denominator to find halves.
+	switch new(big.Int).Lsh(rem, 1).Cmp(denom) {
+	case 1:
+		quo.Add(quo, big.NewInt(1))
+	case 0:
+		if mode != RoundHalfEven || quo.Bit(0) == 1 {
+			quo.Add(quo, big.NewInt(1))
+		}
+	}
+	if scaled.Sign() < 0 {
+		quo.Neg(quo)
+	}
+	return Decimal{r: new(big.Rat).SetFrac(quo, scale)}
+}
+
+// StringFixed formats d with exactly the given number of decimal places. Round
+// the value first; any remaining digits are rounded half away from zero.
+func (d Decimal) StringFixed(places int) string {
+	return d.rat().FloatString(places)
+}
+
+// Float64 returns the nearest float64, for places that only need an
+// approximation (e.g. layout decisions).
+func (d Decimal) Float64() float64 {
+	f, _ := d.rat().Float64()
+	return f
+}
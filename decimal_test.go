@@ -0,0 +1,52 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestDecimalRound(t *testing.T) {
+	tests := []struct {
+		value  string
+		places int
+		mode   RoundingMode
+		want   string
+	}{
+		{"1.005", 2, RoundHalfUp, "1.01"},
+		{"1.005", 2, RoundHalfEven, "1.00"},
+		{"1.015", 2, RoundHalfEven, "1.02"},
+		{"1.0049", 2, RoundHalfUp, "1.00"},
+		{"1.0051", 2, RoundHalfEven, "1.01"},
+		{"-1.005", 2, RoundHalfUp, "-1.01"},
+		{"-1.005", 2, RoundHalfEven, "-1.00"},
+		{"-1.015", 2, RoundHalfEven, "-1.02"},
+		{"-0.004", 2, RoundHalfUp, "0.00"},
+		{"2.5", 0, RoundHalfUp, "3"},
+		{"2.5", 0, RoundHalfEven, "2"},
+		{"3.5", 0, RoundHalfEven, "4"},
+		{"0", 2, RoundHalfUp, "0.00"},
+	}
+	for _, tt := range tests {
+		d := decimalFromString(t, tt.value)
+		if got := d.Round(tt.places, tt.mode).StringFixed(tt.places); got != tt.want {
+			t.Errorf("Round(%s, %d, %s) = %s, want %s", tt.value, tt.places, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestDecimalRoundOneThird(t *testing.T) {
+	third := decimalFromInt(1).Quo(decimalFromInt(3))
+	if got := third.Round(2, RoundHalfUp).StringFixed(2); got != "0.33" {
+		t.Errorf("Round(1/3) = %s, want 0.33", got)
+	}
+}
+
+// decimalFromString parses a decimal literal for tests.
+func decimalFromString(t *testing.T, s string) Decimal {
+	t.Helper()
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		t.Fatalf("bad decimal %q", s)
+	}
+	return Decimal{r: r}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/signintech/gopdf"
+	"github.com/signintech/gopdf/fontmaker/core"
+)
+
+// Font families used with setFont.
+const (
+	fontRegular = "regular"
+	fontBold    = "bold"
+	fontItalic  = "italic"
+)
+
+// invoiceFont is one font face of the invoice and where it was loaded from.
+type invoiceFont struct {
+	family string
+	source string
+	data   []byte
+	cmap   *core.TTFParser // parsed on first use
+}
+
+func (f *invoiceFont) parsed() *core.TTFParser {
+	if f.cmap == nil {
+		f.cmap = &core.TTFParser{}
+		_ = f.cmap.ParseFontData(f.data)
+	}
+	return f.cmap
+}
+
+// covers reports whether the font has a glyph for r.
+func (f *invoiceFont) covers(r rune) bool {
+	if _, ok := f.parsed().Chars()[int(r)]; ok {
+		return true
+	}
+	for _, group := range f.parsed().GroupingTables() {
+		if uint(r) >= group.StartCharCode && uint(r) <= group.EndCharCode {
+			return true
+		}
+	}
+	return false
+}
+
+// ascent returns how far the font's text rises above the baseline at size.
+// gopdf places cells by their top, so runs in different fonts need it to
+// share a baseline.
+func (f *invoiceFont) ascent(size float64) float64 {
+	p := f.parsed()
+	if p.UnitsPerEm() == 0 {
+		return 0
+	}
+	return float64(p.TypoAscender()) * size / float64(p.UnitsPerEm())
+}
+
+// invoiceFonts are the faces added to every document; the embedded Inter
+// faces unless font files are configured.
+var invoiceFonts = []*invoiceFont{
+	{family: fontRegular, source: "Inter", data: interFont},
+	{family: fontBold, source: "Inter Bold", data: interBoldFont},
+	{family: fontItalic, source: "Inter Italic", data: interItalicFont},
+}
+
+// fallbackFonts are tried in order for characters the selected face has no
+// glyph for, e.g. CJK or Arabic text. They are used for every style.
+var fallbackFonts []*invoiceFont
+
+// scriptFontPaths are fonts shipped with common systems that cover Arabic,
+// including its presentation forms, and Hebrew. An invoice in a right-to-left
+// language without fallbackFonts uses the first one found.
+var scriptFontPaths = []string{
+	"/usr/share/fonts/truetype/dejavu/DejaVuSans.ttf",
+	"/usr/share/fonts/dejavu/DejaVuSans.ttf",
+	"/usr/share/fonts/dejavu-sans-fonts/DejaVuSans.ttf",
+	"/usr/share/fonts/TTF/DejaVuSans.ttf",
+	"/usr/local/share/fonts/DejaVuSans.ttf",
+	"/System/Library/Fonts/Supplemental/Arial Unicode.ttf",
+	"/Library/Fonts/Arial Unicode.ttf",
+	`C:\Windows\Fonts\arial.ttf`,
+}
+
+// defaultFallbackFonts returns the configured fallback fonts or, when there
+// are none and one of the invoice languages is written right to left, a
+// system font from scriptFontPaths.
+func defaultFallbackFonts(configured []string, langs ...string) []string {
+	if len(configured) > 0 || rtlInvoiceLang(langs...) == "" {
+		return configured
+	}
+	for _, path := range scriptFontPaths {
+		if _, err := os.Stat(path); err == nil {
+			return []string{path}
+		}
+	}
+	return nil
+}
+
+// rtlInvoiceLang returns the first of langs written right to left, or "".
+func rtlInvoiceLang(langs ...string) string {
+	for _, lang := range langs {
+		if isRTLLanguage(lang) {
+			return lang
+		}
+	}
+	return ""
+}
+
+// missingGlyphs collects, per font family, the characters of the invoice text
+// that no font of the chain has a glyph for.
+var missingGlyphs = map[string]map[rune]bool{}
+
+// loadFonts reads the configured TTF/OTF files. Bold and italic fall back to
+// the regular font file when only that one is set, so a custom font isn't
+// mixed with Inter.
+func loadFonts(regular, bold, italic string, fallbacks []string) error {
+	if bold == "" {
+		bold = regular
+	}
+	if italic == "" {
+		italic = regular
+	}
+	paths := map[string]string{fontRegular: regular, fontBold: bold, fontItalic: italic}
+	for i, f := range invoiceFonts {
+		path := paths[f.family]
+		if path == "" {
+			continue
+		}
+		font, err := readFont(f.family, path)
+		if err != nil {
+			return err
+		}
+		invoiceFonts[i] = font
+	}
+	fallbackFonts = nil
+	for i, path := range fallbacks {
+		font, err := readFont(fmt.Sprintf("fallback%d", i+1), path)
+		if err != nil {
+			return err
+		}
+		fallbackFonts = append(fallbackFonts, font)
+	}
+	return nil
+}
+
+func readFont(family, path string) (*invoiceFont, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read font file %s: %w", path, err)
+	}
+	// parse it once here so a bad file is reported by name
+	probe := &gopdf.GoPdf{}
+	probe.Start(gopdf.Config{PageSize: *gopdf.PageSizeA4})
+	if err := probe.AddTTFFontData(family, data); err != nil {
+		return nil, fmt.Errorf("unable to load font file %s: %w (only fonts with TrueType outlines are supported)", path, err)
+	}
+	return &invoiceFont{family: family, source: path, data: data}, nil
+}
+
+// fontFamily returns the loaded font of a family.
+func fontFamily(family string) *invoiceFont {
+	for _, f := range append(invoiceFonts, fallbackFonts...) {
+		if f.family == family {
+			return f
+		}
+	}
+	return nil
+}
+
+// addFonts adds the invoice fonts to a document, recording any glyphs the
+// text needs but the fonts lack.
+func addFonts(pdf *gopdf.GoPdf) error {
+	for _, f := range append(invoiceFonts, fallbackFonts...) {
+		family := f.family
+		option := gopdf.TtfOption{
+			OnGlyphNotFound: func(r rune) {
+				if unicode.IsControl(r) {
+					return
+				}
+				if missingGlyphs[family] == nil {
+					missingGlyphs[family] = map[rune]bool{}
+				}
+				missingGlyphs[family][r] = true
+			},
+		}
+		if err := pdf.AddTTFFontDataWithOption(family, f.data, option); err != nil {
+			return fmt.Errorf("unable to load font %s: %w", f.source, err)
+		}
+	}
+	return nil
+}
+
+// checkGlyphs reports the characters of the invoice that no font could
+// print, so missing glyphs fail loudly instead of rendering as boxes.
+func checkGlyphs() error {
+	for _, f := range invoiceFonts {
+		missing := missingGlyphs[f.family]
+		if len(missing) == 0 {
+			continue
+		}
+		runes := []rune{}
+		for r := range missing {
+			runes = append(runes, r)
+		}
+		sort.Slice(runes, func(i, j int) bool { return runes[i] < runes[j] })
+		chars := []string{}
+		for _, r := range runes {
+			chars = append(chars, fmt.Sprintf("%q (U+%04X)", r, r))
+		}
+		if len(fallbackFonts) > 0 {
+			return fmt.Errorf("font %s and its fallback fonts have no glyphs for %s used in the invoice text", f.source, strings.Join(chars, ", "))
+		}
+		if lang := rtlInvoiceLang(file.Lang, file.Lang2); lang != "" {
+			return fmt.Errorf("font %s has no glyphs for %s used in the invoice text: language %s needs a font for its script and none was found on this system (add a fallbackFonts entry such as DejaVu Sans or Amiri)", f.source, strings.Join(chars, ", "), lang)
+		}
+		return fmt.Errorf("font %s has no glyphs for %s used in the invoice text (add a fallbackFonts entry that covers them)", f.source, strings.Join(chars, ", "))
+	}
+	return nil
+}
@@ -20,7 +20,14 @@ func importData(path string, structure *Invoice, flags *pflag.FlagSet) error {
 	var b []byte
 	var byteBuffer [][]byte
 	flags.Visit(func(f *pflag.Flag) {
-		if f.Value.Type() != "string" {
+		if itemFlagNames[f.Name] {
+			return
+		}
+		if f.Value.Type() == "stringArray" {
+			values, _ := flags.GetStringArray(f.Name)
+			list, _ := json.Marshal(values)
+			b = []byte(fmt.Sprintf(`{"%s":%s}`, f.Name, list))
+		} else if f.Value.Type() != "string" {
 			b = []byte(fmt.Sprintf(`{"%s":%s}`, f.Name, f.Value))
 		} else {
 			b = []byte(fmt.Sprintf(`{"%s":"%s"}`, f.Name, f.Value))
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// forceOutput (--force) replaces an existing PDF, and newVersion
+	// (--newVersion) writes next to it as <id>-<lang>-v2.pdf and so on.
+	forceOutput bool
+	newVersion  bool
+)
+
+// issuedDocument records a PDF written to the output directory: a hash of its
+// content and one of its amounts, so that a later render with different
+// amounts can be flagged.
+type issuedDocument struct {
+	SHA256  string `yaml:"sha256"`
+	Amounts string `yaml:"amounts"`
+	Due     string `yaml:"due"`
+	Written string `yaml:"written"`
+}
+
+// issuedPath returns the record of the documents in an output directory.
+func issuedPath(outDir string) string {
+	return filepath.Join(outDir, "issued.yaml")
+}
+
+// lockIssued locks the record of an output directory. generate holds it from
+// checking for an existing PDF until the new one is recorded, so that runs at
+// the same time neither write the same file nor lose each other's records.
+func lockIssued(outDir string) (func(), error) {
+	return lockFile(issuedPath(outDir))
+}
+
+// loadIssued reads the record of an output directory, keyed by file name. A
+// missing file is an empty record.
+func loadIssued(outDir string) (map[string]issuedDocument, error) {
+	docs := map[string]issuedDocument{}
+	path := issuedPath(outDir)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return docs, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read issued documents %s: %w", path, err)
+	}
+	if err := yaml.Unmarshal(data, &docs); err != nil {
+		return nil, fmt.Errorf("unable to parse issued documents %s: %w", path, err)
+	}
+	if docs == nil {
+		docs = map[string]issuedDocument{}
+	}
+	return docs, nil
+}
+
+// recordIssued adds a written PDF to the record of its directory.
+func recordIssued(outputPath string, totals Totals) error {
+	outDir, name := filepath.Split(outputPath)
+	docs, err := loadIssued(outDir)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(outputPath)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	docs[name] = issuedDocument{
+		SHA256:  hex.EncodeToString(sum[:]),
+		Amounts: amountsHash(totals),
+		Due:     totals.Due.StringFixed(moneyPlaces) + " " + file.Currency,
+		Written: time.Now().Format(time.RFC3339),
+	}
+	out, err := yaml.Marshal(docs)
+	if err != nil {
+		return err
+	}
+	path := issuedPath(outDir)
+	if err := writeFileAtomic(path, out); err != nil {
+		return fmt.Errorf("unable to write issued documents %s: %w", path, err)
+	}
+	return nil
+}
+
+// amountsHash hashes the currency and the amounts of every line and of the
+// totals, which is what must not change once an invoice has been sent.
+func amountsHash(totals Totals) string {
+	var b strings.Builder
+	fmt.Fprintln(&b, file.Currency)
+	for _, line := range totals.Lines {
+		fmt.Fprintln(&b, line.Net.StringFixed(moneyPlaces), line.Tax.StringFixed(moneyPlaces), line.Gross.StringFixed(moneyPlaces))
+	}
+	for _, amount := range []Decimal{totals.Net, totals.Tax, totals.Discount, totals.Gross, totals.Withholding, totals.Paid, totals.Due} {
+		fmt.Fprintln(&b, amount.StringFixed(moneyPlaces))
+	}
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+// checkOutput decides where an invoice is written when outputPath exists:
+// nowhere unless --force or --newVersion is given. It warns when the amounts
+// differ from those recorded for the existing file.
+func checkOutput(outputPath string, totals Totals) (string, error) {
+	if forceOutput && newVersion {
+		return "", fmt.Errorf("use either --force or --newVersion")
+	}
+	if _, err := os.Stat(outputPath); err != nil {
+		return outputPath, nil
+	}
+	latest, next := outputPath, outputPath
+	if newVersion {
+		latest, next = outputVersions(outputPath)
+	}
+
+	outDir, name := filepath.Split(latest)
+	docs, err := loadIssued(outDir)
+	if err != nil {
+		return "", err
+	}
+	changed := ""
+	if doc, ok := docs[name]; ok && doc.Amounts != amountsHash(totals) {
+		changed = fmt.Sprintf("%s was issued with different amounts (due %s, now %s %s)",
+			latest, doc.Due, totals.Due.StringFixed(moneyPlaces), file.Currency)
+	}
+
+	if !forceOutput && !newVersion {
+		if changed != "" {
+			return "", fmt.Errorf("%s already exists and %s; use --force to replace it or --newVersion to write a new version", outputPath, strings.TrimPrefix(changed, latest+" "))
+		}
+		return "", fmt.Errorf("%s already exists; use --force to replace it or --newVersion to write a new version", outputPath)
+	}
+	if changed != "" {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", changed)
+	}
+	return next, nil
+}
+
+// outputVersions returns the latest existing version of a PDF (x.pdf,
+// x-v2.pdf, ...) and the path of the next one.
+func outputVersions(outputPath string) (string, string) {
+	base := strings.TrimSuffix(outputPath, ".pdf")
+	latest := outputPath
+	for version := 2; ; version++ {
+		path := fmt.Sprintf("%s-v%d.pdf", base, version)
+		if _, err := os.Stat(path); err != nil {
+			return latest, path
+		}
+		latest = path
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAmountsHash(t *testing.T) {
+	saved := file
+	defer func() { file = saved }()
+	file.Currency = "EUR"
+	totals := Totals{
+		Lines: []LineTotals{{Net: decimalFromString(t, "100"), Tax: decimalFromString(t, "23"), Gross: decimalFromString(t, "123")}},
+		Net:   decimalFromString(t, "100"),
+		Tax:   decimalFromString(t, "23"),
+		Gross: decimalFromString(t, "123"),
+		Due:   decimalFromString(t, "123"),
+	}
+	hash := amountsHash(totals)
+	if again := amountsHash(totals); again != hash {
+		t.Errorf("amountsHash is not stable: %s, then %s", hash, again)
+	}
+
+	changed := totals
+	changed.Due = decimalFromString(t, "120")
+	if amountsHash(changed) == hash {
+		t.Error("amountsHash ignores a change of the amount due")
+	}
+	changed = totals
+	changed.Lines = []LineTotals{{Net: decimalFromString(t, "90"), Tax: decimalFromString(t, "33"), Gross: decimalFromString(t, "123")}}
+	if amountsHash(changed) == hash {
+		t.Error("amountsHash ignores a change of a line")
+	}
+	file.Currency = "PLN"
+	if amountsHash(totals) == hash {
+		t.Error("amountsHash ignores a change of currency")
+	}
+}
+
+func TestOutputVersions(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "x-en.pdf")
+	latest, next := outputVersions(path)
+	if latest != path || next != filepath.Join(dir, "x-en-v2.pdf") {
+		t.Errorf("outputVersions with no versions = %s, %s", latest, next)
+	}
+	for _, name := range []string{"x-en.pdf", "x-en-v2.pdf", "x-en-v3.pdf"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	latest, next = outputVersions(path)
+	if latest != filepath.Join(dir, "x-en-v3.pdf") || next != filepath.Join(dir, "x-en-v4.pdf") {
+		t.Errorf("outputVersions = %s, %s, want x-en-v3.pdf, x-en-v4.pdf", latest, next)
+	}
+}
+
+func TestRecordIssued(t *testing.T) {
+	saved, savedForce, savedVersion := file, forceOutput, newVersion
+	defer func() { file, forceOutput, newVersion = saved, savedForce, savedVersion }()
+	file.Currency = "EUR"
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "x-en.pdf")
+	content := []byte("%PDF-1.4 test")
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	totals := Totals{Due: decimalFromString(t, "123")}
+	if err := recordIssued(path, totals); err != nil {
+		t.Fatal(err)
+	}
+	docs, err := loadIssued(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, ok := docs["x-en.pdf"]
+	if !ok {
+		t.Fatalf("x-en.pdf is not recorded: %v", docs)
+	}
+	sum := sha256.Sum256(content)
+	if doc.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("sha256 = %s, want the hash of the file", doc.SHA256)
+	}
+	if doc.Amounts != amountsHash(totals) || doc.Due != "123.00 EUR" {
+		t.Errorf("recorded amounts %s, due %q", doc.Amounts, doc.Due)
+	}
+
+	forceOutput, newVersion = false, false
+	if _, err := checkOutput(path, totals); err == nil || strings.Contains(err.Error(), "different amounts") {
+		t.Errorf("checkOutput of the same amounts: err = %v, want an exists error", err)
+	}
+	other := Totals{Due: decimalFromString(t, "100")}
+	if _, err := checkOutput(path, other); err == nil || !strings.Contains(err.Error(), "different amounts") {
+		t.Errorf("checkOutput of other amounts: err = %v, want a different amounts error", err)
+	}
+	newVersion = true
+	if next, err := checkOutput(path, other); err != nil || next != filepath.Join(dir, "x-en-v2.pdf") {
+		t.Errorf("checkOutput with newVersion = %s, %v, want x-en-v2.pdf", next, err)
+	}
+	forceOutput, newVersion = true, false
+	if next, err := checkOutput(path, other); err != nil || next != path {
+		t.Errorf("checkOutput with force = %s, %v, want %s", next, err, path)
+	}
+	if next, err := checkOutput(filepath.Join(dir, "y-en.pdf"), other); err != nil || next != filepath.Join(dir, "y-en.pdf") {
+		t.Errorf("checkOutput of a new file = %s, %v", next, err)
+	}
+}
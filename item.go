@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// LineItem is a single billed position on the invoice.
+type LineItem struct {
+	Description string  `json:"description" yaml:"description"`
+	Quantity    float64 `json:"quantity" yaml:"quantity"`
+	Unit        string  `json:"unit" yaml:"unit"`
+	UnitPrice   float64 `json:"unitPrice" yaml:"unitPrice"`
+	SKU         string  `json:"sku" yaml:"sku"`
+	Notes       string  `json:"notes" yaml:"notes"`
+
+	// TaxRate overrides the invoice-wide tax rate for this line (0.23 = 23%).
+	// TaxCategory is an optional code such as S, AA, Z or E shown next to it.
+	TaxRate     *float64 `json:"taxRate" yaml:"taxRate"`
+	TaxCategory string   `json:"taxCategory" yaml:"taxCategory"`
+
+	// Discount is a fraction of the line amount (0.1 = 10%) and DiscountAmount
+	// a fixed amount off it; both may be combined.
+	Discount       float64 `json:"discount" yaml:"discount"`
+	DiscountAmount float64 `json:"discountAmount" yaml:"discountAmount"`
+
+	// legacy marks items given as plain strings in the old parallel
+	// items/quantities/rates form; they get their quantity and price later.
+	legacy bool
+}
+
+// lineItemFields is LineItem without its methods, used to decode objects
+// without recursing into UnmarshalJSON/UnmarshalYAML.
+type lineItemFields LineItem
+
+// UnmarshalJSON accepts either a plain string (old form) or an item object.
+// An object without "quantity" defaults to a quantity of 1.
+func (li *LineItem) UnmarshalJSON(data []byte) error {
+	var description string
+	if err := json.Unmarshal(data, &description); err == nil {
+		*li = LineItem{Description: description, legacy: true}
+		return nil
+	}
+	fields := lineItemFields{Quantity: 1}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	*li = LineItem(fields)
+	return nil
+}
+
+// UnmarshalYAML mirrors UnmarshalJSON for YAML input.
+func (li *LineItem) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*li = LineItem{Description: value.Value, legacy: true}
+		return nil
+	}
+	fields := lineItemFields{Quantity: 1}
+	if err := value.Decode(&fields); err != nil {
+		return err
+	}
+	*li = LineItem(fields)
+	return nil
+}
+
+// itemFlagNames are the CLI flags that describe line items. They are applied
+// by applyItemFlags rather than by the generic flag overlay in importData.
+var itemFlagNames = map[string]bool{
+	"item":     true,
+	"quantity": true,
+	"rate":     true,
+}
+
+// Values of the --item, --quantity and --rate flags.
+var (
+	itemFlags     []string
+	quantityFlags []float64
+	rateFlags     []float64
+)
+
+// legacyLineItems zips the old parallel slices into line items. Quantities may
+// be omitted entirely (every item then counts once); otherwise both slices must
+// have exactly one entry per item.
+func legacyLineItems(descriptions []string, quantities []float64, rates []float64) ([]LineItem, error) {
+	if len(quantities) != 0 && len(quantities) != len(descriptions) {
+		return nil, fmt.Errorf("got %d items but %d quantities", len(descriptions), len(quantities))
+	}
+	if len(rates) != len(descriptions) {
+		return nil, fmt.Errorf("got %d items but %d rates", len(descriptions), len(rates))
+	}
+	items := make([]LineItem, len(descriptions))
+	for i, description := range descriptions {
+		q := 1.0
+		if len(quantities) != 0 {
+			q = quantities[i]
+		}
+		items[i] = LineItem{Description: description, Quantity: q, UnitPrice: rates[i]}
+	}
+	return items, nil
+}
+
+// resolveLineItems converts an invoice imported in the old parallel-slice form
+// into line items. Mixing item objects with quantities/rates is rejected.
+func resolveLineItems(inv *Invoice) error {
+	legacy := len(inv.Quantities) > 0 || len(inv.Rates) > 0
+	for _, item := range inv.Items {
+		if item.legacy {
+			legacy = true
+		}
+	}
+	if !legacy {
+		return nil
+	}
+
+	descriptions := make([]string, len(inv.Items))
+	for i, item := range inv.Items {
+		if !item.legacy {
+			return fmt.Errorf("items: cannot mix item objects with the quantities/rates form")
+		}
+		descriptions[i] = item.Description
+	}
+	items, err := legacyLineItems(descriptions, inv.Quantities, inv.Rates)
+	if err != nil {
+		return fmt.Errorf("items: %w", err)
+	}
+	inv.Items = items
+	inv.Quantities = nil
+	inv.Rates = nil
+	return nil
+}
+
+// applyItemFlags applies --item, --quantity and --rate. Without an import they
+// always define the items; with an import only explicitly set flags are used.
+// When --item is given, quantities and rates must be given alongside it.
+func applyItemFlags(inv *Invoice, flags *pflag.FlagSet, imported bool) error {
+	if !imported || flags.Changed("item") {
+		quantities := quantityFlags
+		if flags.Changed("item") && !flags.Changed("quantity") {
+			quantities = nil
+		}
+		rates := rateFlags
+		if flags.Changed("item") && !flags.Changed("rate") {
+			rates = nil
+		}
+		items, err := legacyLineItems(itemFlags, quantities, rates)
+		if err != nil {
+			return fmt.Errorf("items: %w", err)
+		}
+		inv.Items = items
+		return nil
+	}
+
+	if flags.Changed("quantity") {
+		if len(quantityFlags) != len(inv.Items) {
+			return fmt.Errorf("items: got %d items but %d quantities", len(inv.Items), len(quantityFlags))
+		}
+		for i := range inv.Items {
+			inv.Items[i].Quantity = quantityFlags[i]
+		}
+	}
+	if flags.Changed("rate") {
+		if len(rateFlags) != len(inv.Items) {
+			return fmt.Errorf("items: got %d items but %d rates", len(inv.Items), len(rateFlags))
+		}
+		for i := range inv.Items {
+			inv.Items[i].UnitPrice = rateFlags[i]
+		}
+	}
+	return nil
+}
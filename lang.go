@@ -0,0 +1,328 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// builtinLangs are the language packs shipped in lang/, embedded so the binary
+// works from any directory.
+//
+//go:embed lang/*.json
+var builtinLangs embed.FS
+
+// langDir is a directory of user language packs (--lang-dir). Its packs take
+// precedence over those in the config directory.
+var langDir string
+
+// invoiceLangs are the languages loadLang loaded for the invoice: the first,
+// and the second of a bilingual invoice.
+var invoiceLangs = []string{"en"}
+
+// englishLangValidated tracks whether we've already validated the English pack.
+var englishLangValidated bool
+
+var langCodePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// configDir returns the directory of the user's invoice settings, e.g.
+// ~/.config/invoice on Linux. It is empty when the system has none.
+func configDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "invoice")
+}
+
+// userLangDirs returns the directories of user language packs, in the order
+// they are applied.
+func userLangDirs() []string {
+	dirs := []string{}
+	if dir := configDir(); dir != "" {
+		dirs = append(dirs, filepath.Join(dir, "lang"))
+	}
+	if langDir != "" {
+		dirs = append(dirs, langDir)
+	}
+	return dirs
+}
+
+// canonicalLangTag writes a BCP 47 tag in its usual case, e.g. "pt_br" as
+// "pt-BR", which is also the name of its pack file.
+func canonicalLangTag(tag string) string {
+	parts := strings.Split(strings.ReplaceAll(tag, "_", "-"), "-")
+	for i, part := range parts {
+		switch {
+		case i > 0 && len(part) == 2:
+			parts[i] = strings.ToUpper(part)
+		case i > 0 && len(part) == 4:
+			parts[i] = strings.ToUpper(part[:1]) + strings.ToLower(part[1:])
+		default:
+			parts[i] = strings.ToLower(part)
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+// langChain returns the packs a language is looked up in, most specific
+// first: the tag, the tag with its subtags dropped one by one, then English,
+// e.g. de-AT, de, en.
+func langChain(tag string) []string {
+	chain := []string{}
+	parts := strings.Split(canonicalLangTag(tag), "-")
+	for n := len(parts); n > 0; n-- {
+		chain = append(chain, strings.Join(parts[:n], "-"))
+	}
+	if chain[len(chain)-1] != "en" {
+		chain = append(chain, "en")
+	}
+	return chain
+}
+
+// baseLang returns the language subtag of a tag, e.g. "de" for "de-AT".
+func baseLang(tag string) string {
+	return strings.ToLower(strings.SplitN(strings.ReplaceAll(tag, "_", "-"), "-", 2)[0])
+}
+
+// langKeys returns the keys of a language pack in LangStrings order.
+func langKeys() []string {
+	keys := []string{}
+	t := reflect.TypeOf(LangStrings{})
+	for i := 0; i < t.NumField(); i++ {
+		keys = append(keys, strings.Split(t.Field(i).Tag.Get("json"), ",")[0])
+	}
+	return keys
+}
+
+// langFile is one file of a language pack.
+type langFile struct {
+	name string
+	data []byte
+}
+
+// langFiles returns the files of a language pack in the order they are
+// applied: the built-in one, if any, then those of the user pack directories.
+func langFiles(code string) ([]langFile, error) {
+	if !langCodePattern.MatchString(code) {
+		return nil, fmt.Errorf("invalid language code %q", code)
+	}
+	files := []langFile{}
+	if data, err := builtinLangs.ReadFile("lang/" + code + ".json"); err == nil {
+		files = append(files, langFile{name: "built-in lang/" + code + ".json", data: data})
+	}
+	for _, dir := range userLangDirs() {
+		path := filepath.Join(dir, code+".json")
+		data, err := os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to read language file %s: %w", path, err)
+		}
+		files = append(files, langFile{name: path, data: data})
+	}
+	return files, nil
+}
+
+// readLang reads a single language pack: the built-in pack for the code, if
+// any, with the keys of the user packs laid over it one by one. It reports
+// whether any pack for the code exists.
+func readLang(code string) (map[string]string, bool, error) {
+	pack := map[string]string{}
+	files, err := langFiles(code)
+	if err != nil {
+		return pack, false, err
+	}
+	for _, f := range files {
+		if err := json.Unmarshal(f.data, &pack); err != nil {
+			return pack, false, fmt.Errorf("unable to parse language file %s: %w", f.name, err)
+		}
+	}
+	return pack, len(files) > 0, nil
+}
+
+// langStringsOf converts a pack read by readLang.
+func langStringsOf(pack map[string]string) LangStrings {
+	var ls LangStrings
+	data, _ := json.Marshal(pack)
+	_ = json.Unmarshal(data, &ls)
+	return ls
+}
+
+// ensureEnglishLang ensures that the English pack, with any user overrides,
+// is complete. The app will not run without a valid English language pack.
+func ensureEnglishLang() error {
+	if englishLangValidated {
+		return nil
+	}
+	pack, _, err := readLang("en")
+	if err != nil {
+		return err
+	}
+	en := langStringsOf(pack)
+	if err := validateLang(&en, "en"); err != nil {
+		return err
+	}
+	englishLangValidated = true
+	return nil
+}
+
+// loadLang loads the requested language, and with a second language code
+// the labels of both for a bilingual invoice. English is always required and
+// must be valid.
+func loadLang(code, code2, fallback string) error {
+	if err := ensureEnglishLang(); err != nil {
+		return err
+	}
+	if fallback != "error" && fallback != "warn" {
+		return fmt.Errorf("unknown langFallback %q (use error or warn)", fallback)
+	}
+
+	if code == "" {
+		code = "en"
+	}
+	ls, err := resolveLang(code, fallback)
+	if err != nil {
+		return err
+	}
+	if code2 != "" {
+		ls2, err := resolveLang(code2, fallback)
+		if err != nil {
+			return err
+		}
+		ls = bilingualLangStrings(ls, ls2)
+	}
+	langStrings = ls
+	invoiceLangs = []string{code}
+	if code2 != "" {
+		invoiceLangs = append(invoiceLangs, code2)
+	}
+	return nil
+}
+
+// resolveLang reads a language. Keys its pack lacks are taken from the next
+// pack of its fallback chain (see langChain), so a regional variant only
+// needs the keys that differ. Falling back to another language, English, is
+// an error unless fallback is "warn", which prints every key taken from
+// another pack instead. Values lang new marked for translation are skipped,
+// and rejected in the same way.
+func resolveLang(code, fallback string) (LangStrings, error) {
+	chain := langChain(code)
+
+	merged := map[string]string{}
+	source := map[string]string{}
+	marked := map[string]bool{}
+	found := []string{}
+	for _, tag := range chain {
+		pack, ok, err := readLang(tag)
+		if err != nil {
+			return LangStrings{}, err
+		}
+		if !ok {
+			continue
+		}
+		found = append(found, tag)
+		for key, value := range pack {
+			// values scaffolded by lang new and not yet translated never
+			// reach the invoice; the next pack of the chain provides them
+			if strings.HasPrefix(value, translateMarker) {
+				marked[key] = true
+				continue
+			}
+			if numberWordKeys[key] && baseLang(tag) != baseLang(code) {
+				continue
+			}
+			if _, ok := merged[key]; !ok && value != "" {
+				merged[key] = value
+				source[key] = tag
+			}
+		}
+	}
+	if found[0] == "en" && baseLang(code) != "en" {
+		return LangStrings{}, fmt.Errorf("unknown language %q (there is no built-in or user language pack for it)", code)
+	}
+	if len(marked) > 0 {
+		untranslated := []string{}
+		for _, key := range langKeys() {
+			if marked[key] {
+				untranslated = append(untranslated, key)
+			}
+		}
+		if fallback != "warn" {
+			return LangStrings{}, fmt.Errorf("language %s has keys not yet translated: %s (their values start with %q; translate them, or set langFallback to warn to use the parent language)",
+				chain[0], strings.Join(untranslated, ", "), strings.TrimSpace(translateMarker))
+		}
+		fmt.Fprintf(os.Stderr, "warning: language %s has keys not yet translated: %s\n", chain[0], strings.Join(untranslated, ", "))
+	}
+
+	ls := langStringsOf(merged)
+	if err := validateLang(&ls, chain[0]); err != nil {
+		return LangStrings{}, err
+	}
+
+	// keys taken from each pack further down the chain, and those in another language
+	fallbacks := map[string][]string{}
+	foreign := []string{}
+	for _, key := range langKeys() {
+		if tag := source[key]; tag != chain[0] && tag != "" {
+			fallbacks[tag] = append(fallbacks[tag], key)
+			if baseLang(tag) != baseLang(code) {
+				foreign = append(foreign, key)
+			}
+		}
+	}
+	if fallback == "warn" {
+		for _, tag := range found {
+			if keys := fallbacks[tag]; len(keys) > 0 {
+				fmt.Fprintf(os.Stderr, "warning: language %s has no %s; using %s\n", chain[0], strings.Join(keys, ", "), tag)
+			}
+		}
+	} else if len(foreign) > 0 {
+		return LangStrings{}, fmt.Errorf("language %s has no %s (add them to its pack, or set langFallback to warn to use English)", chain[0], strings.Join(foreign, ", "))
+	}
+	return ls, nil
+}
+
+// numberWordKeys spell out the amount in words. They are optional, and never
+// taken from a pack of another language, whose words would not fit.
+var numberWordKeys = map[string]bool{
+	"_numberGrammar":  true,
+	"_numberWords":    true,
+	"_numberTens":     true,
+	"_numberHundreds": true,
+	"_numberScales":   true,
+	"_numberMinus":    true,
+	"_currencyWords":  true,
+}
+
+// singleLangKeys are values printed in table cells rather than labels; a
+// bilingual invoice shows them in the first language only.
+var singleLangKeys = map[string]bool{
+	"_na":               true,
+	"_taxReverseCharge": true,
+	"_taxExempt":        true,
+	"_taxOutsideScope":  true,
+}
+
+// bilingualLangStrings joins the labels of two languages, e.g. "Faktura /
+// Invoice". Labels that are the same in both are printed once.
+func bilingualLangStrings(primary, secondary LangStrings) LangStrings {
+	p := reflect.ValueOf(&primary).Elem()
+	s := reflect.ValueOf(secondary)
+	for i, key := range langKeys() {
+		a, b := p.Field(i).String(), s.Field(i).String()
+		if singleLangKeys[key] || numberWordKeys[key] || a == b {
+			continue
+		}
+		p.Field(i).SetString(a + " / " + b)
+	}
+	return primary
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// translateMarker starts the values of a scaffolded language pack until they
+// are translated.
+const translateMarker = "TRANSLATE: "
+
+var langCmd = &cobra.Command{
+	Use:   "lang",
+	Short: "List, validate and create language packs",
+	Long:  `List, validate and create the language packs that provide the fixed labels of an invoice.`,
+}
+
+var langListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the available languages",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		codes, err := availableLangs()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, code := range codes {
+			files, err := langFiles(code)
+			if err != nil {
+				return err
+			}
+			names := []string{}
+			for _, f := range files {
+				names = append(names, f.name)
+			}
+			fmt.Fprintf(w, "%s\t%s\n", code, strings.Join(names, " + "))
+		}
+		return w.Flush()
+	},
+}
+
+var langValidateCmd = &cobra.Command{
+	Use:   "validate <code>",
+	Short: "Check a language pack for missing, unknown and untranslated keys",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		code := canonicalLangTag(args[0])
+		if err := validateLangPack(code); err != nil {
+			return err
+		}
+		fmt.Printf("Language %s is valid\n", code)
+		return nil
+	},
+}
+
+var langNewCmd = &cobra.Command{
+	Use:   "new <code>",
+	Short: "Create a language pack to translate, pre-filled from English",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		code := canonicalLangTag(args[0])
+		path, err := scaffoldLang(code)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Created %s\n", path)
+		return nil
+	},
+}
+
+func init() {
+	langCmd.AddCommand(langListCmd, langValidateCmd, langNewCmd)
+}
+
+// availableLangs returns the codes of the built-in and user language packs.
+func availableLangs() ([]string, error) {
+	seen := map[string]bool{}
+	entries, _ := builtinLangs.ReadDir("lang")
+	for _, dir := range userLangDirs() {
+		userEntries, err := os.ReadDir(dir)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to read language directory %s: %w", dir, err)
+		}
+		entries = append(entries, userEntries...)
+	}
+	for _, entry := range entries {
+		code, ok := strings.CutSuffix(entry.Name(), ".json")
+		if ok && !entry.IsDir() && langCodePattern.MatchString(code) {
+			seen[code] = true
+		}
+	}
+	codes := []string{}
+	for code := range seen {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes, nil
+}
+
+// validateLangPack checks the files of a language pack for keys LangStrings
+// doesn't have and values still marked for translation, and checks with
+// validateLang that the pack, together with its parent languages, has every
+// key. Taking keys from English doesn't count, as generate rejects that by
+// default.
+func validateLangPack(code string) error {
+	files, err := langFiles(code)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("unknown language %q (there is no built-in or user language pack for it)", code)
+	}
+
+	known := map[string]bool{}
+	for _, key := range langKeys() {
+		known[key] = true
+	}
+	problems := []string{}
+	for _, f := range files {
+		var pack map[string]string
+		if err := json.Unmarshal(f.data, &pack); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", f.name, err))
+			continue
+		}
+		unknown, untranslated := []string{}, []string{}
+		for key := range pack {
+			if !known[key] {
+				unknown = append(unknown, key)
+			}
+		}
+		sort.Strings(unknown)
+		for _, key := range langKeys() {
+			if strings.HasPrefix(pack[key], translateMarker) {
+				untranslated = append(untranslated, key)
+			}
+		}
+		if len(unknown) > 0 {
+			problems = append(problems, fmt.Sprintf("%s: unknown keys %s", f.name, strings.Join(unknown, ", ")))
+		}
+		if len(untranslated) > 0 {
+			problems = append(problems, fmt.Sprintf("%s: not yet translated %s", f.name, strings.Join(untranslated, ", ")))
+		}
+	}
+	if len(problems) == 0 {
+		merged := map[string]string{}
+		for _, tag := range langChain(code) {
+			if tag == "en" && baseLang(code) != "en" {
+				break
+			}
+			pack, _, err := readLang(tag)
+			if err != nil {
+				return err
+			}
+			for key, value := range pack {
+				if merged[key] == "" {
+					merged[key] = value
+				}
+			}
+		}
+		ls := langStringsOf(merged)
+		if err := validateLang(&ls, code); err != nil {
+			problems = append(problems, err.Error())
+		}
+		if ls.NumberGrammar != "" {
+			if _, err := parseNumberWords(ls); err != nil {
+				problems = append(problems, "number words: "+err.Error())
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("language %s has problems:\n  %s", code, strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// scaffoldLang writes a new user language pack with every key set to its
+// English text behind translateMarker. The file goes to --lang-dir when set
+// and the config directory otherwise, and never replaces an existing file.
+func scaffoldLang(code string) (string, error) {
+	if !langCodePattern.MatchString(code) {
+		return "", fmt.Errorf("invalid language code %q", code)
+	}
+	dir := langDir
+	if dir == "" {
+		if configDir() == "" {
+			return "", fmt.Errorf("no config directory on this system; use --lang-dir")
+		}
+		dir = filepath.Join(configDir(), "lang")
+	}
+	path := filepath.Join(dir, code+".json")
+	if _, err := os.Stat(path); err == nil {
+		return "", fmt.Errorf("language file %s already exists", path)
+	}
+
+	pack, _, err := readLang("en")
+	if err != nil {
+		return "", err
+	}
+	for key, value := range pack {
+		pack[key] = translateMarker + value
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "    ")
+	if err := enc.Encode(langStringsOf(pack)); err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("unable to create language directory %s: %w", dir, err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		return "", fmt.Errorf("unable to write language file %s: %w", path, err)
+	}
+	return path, nil
+}
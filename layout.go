@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/signintech/gopdf"
+)
+
+// pageSizes are the paper sizes accepted by --pageSize, keyed by lowercase name.
+var pageSizes = map[string]*gopdf.Rect{
+	"a4":     gopdf.PageSizeA4,
+	"a5":     gopdf.PageSizeA5,
+	"letter": gopdf.PageSizeLetter,
+	"legal":  gopdf.PageSizeLegal,
+}
+
+// Layout holds every page position derived from the page size and margin.
+// The numeric columns of the item table keep their widths (they hold amounts)
+// and sit against the right margin, so wider pages give the item name more
+// room; on pages narrower than A4 portrait they shrink proportionally.
+type Layout struct {
+	PageWidth  float64
+	PageHeight float64
+	Margin     float64
+
+	QuantityColumn float64
+	RateColumn     float64 // unit net
+	AmountColumn   float64 // total net
+	TaxColumn      float64
+	GrossColumn    float64
+
+	// TotalsLabel is where the labels of the totals section start; see
+	// fitTotalsLabels.
+	TotalsLabel float64
+
+	SellerBuyerSplit float64
+	FooterY          float64
+	ContentBottom    float64 // lowest Y body content may reach; the footer sits below it
+}
+
+// layout is the page layout of the invoice being generated.
+var layout = mustLayout("A4", "portrait")
+
+// Widths of the numeric item table columns on A4 portrait, from QTY to TOTAL GROSS.
+const (
+	referenceContentWidth = 515.28
+	quantityColumnWidth   = 50
+	rateColumnWidth       = 70
+	amountColumnWidth     = 70
+	taxColumnWidth        = 50
+	grossColumnWidth      = 75.28
+)
+
+// newLayout computes the layout for a named page size and orientation.
+func newLayout(pageSize, orientation string) (Layout, error) {
+	size, ok := pageSizes[strings.ToLower(pageSize)]
+	if !ok {
+		return Layout{}, fmt.Errorf("unknown page size %q (use A4, A5, Letter or Legal)", pageSize)
+	}
+	width, height := size.W, size.H
+	switch strings.ToLower(orientation) {
+	case "", "portrait":
+	case "landscape":
+		width, height = height, width
+	default:
+		return Layout{}, fmt.Errorf("unknown orientation %q (use portrait or landscape)", orientation)
+	}
+
+	l := Layout{PageWidth: width, PageHeight: height, Margin: 40}
+	contentWidth := width - 2*l.Margin
+	scale := contentWidth / referenceContentWidth
+	if scale > 1 {
+		scale = 1
+	}
+	l.GrossColumn = width - l.Margin - grossColumnWidth*scale
+	l.TaxColumn = l.GrossColumn - taxColumnWidth*scale
+	l.AmountColumn = l.TaxColumn - amountColumnWidth*scale
+	l.RateColumn = l.AmountColumn - rateColumnWidth*scale
+	l.QuantityColumn = l.RateColumn - quantityColumnWidth*scale
+	l.TotalsLabel = l.AmountColumn + 18
+	l.SellerBuyerSplit = l.Margin + contentWidth*250/referenceContentWidth
+	l.FooterY = height - 42
+	l.ContentBottom = l.FooterY - 30
+	return l, nil
+}
+
+func mustLayout(pageSize, orientation string) Layout {
+	l, err := newLayout(pageSize, orientation)
+	if err != nil {
+		panic(err)
+	}
+	return l
+}
+
+// Right returns the X of the right margin.
+func (l Layout) Right() float64 {
+	return l.PageWidth - l.Margin
+}
+
+// TotalsLabelX returns where the labels of the totals section start.
+func (l Layout) TotalsLabelX() float64 {
+	return l.TotalsLabel
+}
+
+// pageRect returns the page size for gopdf.
+func (l Layout) pageRect() gopdf.Rect {
+	return gopdf.Rect{W: l.PageWidth, H: l.PageHeight}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Locale holds the rules for printing numbers, amounts and dates.
+type Locale struct {
+	Decimal   string // decimal separator
+	Thousands string // thousands separator, empty for none
+	// Currency places the currency symbol around an amount, e.g.
+	// "{symbol}{amount}" for $1,234.56 or "{amount} {symbol}" for 1 234,56 zł.
+	Currency string
+	// Date is the Go time layout for dates, e.g. "02.01.2006".
+	Date string
+}
+
+// locales are the built-in formatting rules, keyed by language or language-region tag.
+var locales = map[string]Locale{
+	"en":    {Decimal: ".", Thousands: ",", Currency: "{symbol}{amount}", Date: "2006-01-02"},
+	"en-us": {Decimal: ".", Thousands: ",", Currency: "{symbol}{amount}", Date: "01/02/2006"},
+	"en-gb": {Decimal: ".", Thousands: ",", Currency: "{symbol}{amount}", Date: "02/01/2006"},
+	"pl":    {Decimal: ",", Thousands: " ", Currency: "{amount} {symbol}", Date: "02.01.2006"},
+	"de":    {Decimal: ",", Thousands: ".", Currency: "{amount} {symbol}", Date: "02.01.2006"},
+	"de-ch": {Decimal: ".", Thousands: "'", Currency: "{symbol} {amount}", Date: "02.01.2006"},
+	"fr":    {Decimal: ",", Thousands: " ", Currency: "{amount} {symbol}", Date: "02/01/2006"},
+	"es":    {Decimal: ",", Thousands: ".", Currency: "{amount} {symbol}", Date: "02/01/2006"},
+	"it":    {Decimal: ",", Thousands: ".", Currency: "{amount} {symbol}", Date: "02/01/2006"},
+	"nl":    {Decimal: ",", Thousands: ".", Currency: "{symbol} {amount}", Date: "02-01-2006"},
+	"pt":    {Decimal: ",", Thousands: ".", Currency: "{symbol} {amount}", Date: "02/01/2006"},
+	"cs":    {Decimal: ",", Thousands: " ", Currency: "{amount} {symbol}", Date: "02.01.2006"},
+	"ja":    {Decimal: ".", Thousands: ",", Currency: "{symbol}{amount}", Date: "2006/01/02"},
+	"zh":    {Decimal: ".", Thousands: ",", Currency: "{symbol}{amount}", Date: "2006-01-02"},
+	"ar":    {Decimal: ".", Thousands: ",", Currency: "{amount} {symbol}", Date: "02/01/2006"},
+	"he":    {Decimal: ".", Thousands: ",", Currency: "{amount} {symbol}", Date: "02.01.2006"},
+}
+
+// locale holds the formatting rules of the invoice being generated.
+var locale = locales["en"]
+
+// loadLocale selects the formatting rules: the locale setting when given,
+// otherwise the rules for the invoice language (or its base language), and
+// English rules for languages without their own.
+func loadLocale(tag, lang string) error {
+	if tag != "" {
+		l, ok := findLocale(tag)
+		if !ok {
+			names := []string{}
+			for name := range locales {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return fmt.Errorf("unknown locale %q (use one of %s)", tag, strings.Join(names, ", "))
+		}
+		locale = l
+		return nil
+	}
+	l, ok := findLocale(lang)
+	if !ok {
+		l = locales["en"]
+	}
+	locale = l
+	return nil
+}
+
+// findLocale looks up a tag such as "de-AT", falling back to its language.
+func findLocale(tag string) (Locale, bool) {
+	tag = strings.ToLower(strings.ReplaceAll(tag, "_", "-"))
+	if l, ok := locales[tag]; ok {
+		return l, true
+	}
+	l, ok := locales[strings.SplitN(tag, "-", 2)[0]]
+	return l, ok
+}
+
+// localizeNumber rewrites a plain number such as "-1234.5" with the locale's
+// separators, grouping the integer digits by thousands.
+func localizeNumber(plain string) string {
+	sign := ""
+	if strings.HasPrefix(plain, "-") {
+		sign, plain = "-", plain[1:]
+	}
+	integer, fraction, hasFraction := strings.Cut(plain, ".")
+	if locale.Thousands != "" {
+		grouped := ""
+		for len(integer) > 3 {
+			grouped = locale.Thousands + integer[len(integer)-3:] + grouped
+			integer = integer[:len(integer)-3]
+		}
+		integer += grouped
+	}
+	if hasFraction {
+		return sign + integer + locale.Decimal + fraction
+	}
+	return sign + integer
+}
+
+// formatCurrency renders an amount with the currency symbol placed as the
+// locale does, e.g. "$1,234.56" or "1 234,56 zł". Currencies without a known
+// symbol take their ISO code in its place, e.g. "CHF 1,234.56".
+func formatCurrency(amount Decimal) string {
+	symbol := currencySymbols[file.Currency]
+	placement := locale.Currency
+	if symbol == "" {
+		if file.Currency == "" {
+			return formatMoney(amount)
+		}
+		symbol = file.Currency
+		placement = strings.Replace(placement, "{symbol}{amount}", "{symbol} {amount}", 1)
+	}
+	sign := ""
+	if amount.Sign() < 0 {
+		sign, amount = "-", amount.Neg()
+	}
+	return sign + strings.NewReplacer("{symbol}", symbol, "{amount}", formatMoney(amount)).Replace(placement)
+}
+
+// formatDate renders an ISO date (YYYY-MM-DD) with the locale's date pattern.
+// Dates in any other form are printed as written.
+func formatDate(date string) string {
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return date
+	}
+	return t.Format(locale.Date)
+}
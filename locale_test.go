@@ -0,0 +1,102 @@
+package main
+
+import "testing"
+
+func TestLocalizeNumber(t *testing.T) {
+	saved := locale
+	defer func() { locale = saved }()
+	tests := []struct {
+		locale, plain, want string
+	}{
+		{"en", "1234567.89", "1,234,567.89"},
+		{"en", "-1234.5", "-1,234.5"},
+		{"en", "999", "999"},
+		{"pl", "1234567.89", "1 234 567,89"},
+		{"pl", "-1234", "-1 234"},
+		{"pl", "0.5", "0,5"},
+		{"de", "1234567.89", "1.234.567,89"},
+		{"de", "100.00", "100,00"},
+		{"de-ch", "1234.56", "1'234.56"},
+	}
+	for _, tt := range tests {
+		locale = locales[tt.locale]
+		if got := localizeNumber(tt.plain); got != tt.want {
+			t.Errorf("%s: localizeNumber(%q) = %q, want %q", tt.locale, tt.plain, got, tt.want)
+		}
+	}
+}
+
+func TestFormatCurrency(t *testing.T) {
+	savedLocale, savedFile := locale, file
+	defer func() { locale, file = savedLocale, savedFile }()
+	tests := []struct {
+		locale, currency, amount, want string
+	}{
+		{"en", "USD", "1234.56", "$1,234.56"},
+		{"en", "USD", "-1234.56", "-$1,234.56"},
+		{"en", "CHF", "1234.56", "CHF 1,234.56"},
+		{"pl", "PLN", "1234.56", "1 234,56 zł"},
+		{"pl", "PLN", "-0.5", "-0,50 zł"},
+		{"pl", "CHF", "1234.56", "1 234,56 CHF"},
+		{"de", "EUR", "1234.56", "1.234,56 €"},
+		{"de", "EUR", "0", "0,00 €"},
+		{"de", "CHF", "1234.56", "1.234,56 CHF"},
+		{"de-ch", "CHF", "1234.56", "CHF 1'234.56"},
+		{"en", "", "1234.56", "1,234.56"},
+	}
+	for _, tt := range tests {
+		locale = locales[tt.locale]
+		file = Invoice{Currency: tt.currency}
+		if got := formatCurrency(decimalFromString(t, tt.amount)); got != tt.want {
+			t.Errorf("%s %s: formatCurrency(%s) = %q, want %q", tt.locale, tt.currency, tt.amount, got, tt.want)
+		}
+	}
+}
+
+func TestFormatDate(t *testing.T) {
+	saved := locale
+	defer func() { locale = saved }()
+	tests := []struct {
+		locale, date, want string
+	}{
+		{"en", "2026-02-03", "2026-02-03"},
+		{"en-us", "2026-02-03", "02/03/2026"},
+		{"en-gb", "2026-02-03", "03/02/2026"},
+		{"pl", "2026-02-03", "03.02.2026"},
+		{"de", "2026-12-31", "31.12.2026"},
+		{"de", "3 Feb 2026", "3 Feb 2026"},
+		{"pl", "", ""},
+	}
+	for _, tt := range tests {
+		locale = locales[tt.locale]
+		if got := formatDate(tt.date); got != tt.want {
+			t.Errorf("%s: formatDate(%q) = %q, want %q", tt.locale, tt.date, got, tt.want)
+		}
+	}
+}
+
+func TestLoadLocale(t *testing.T) {
+	saved := locale
+	defer func() { locale = saved }()
+	tests := []struct {
+		tag, lang string
+		want      Locale
+		wantErr   bool
+	}{
+		{"", "pl", locales["pl"], false},
+		{"", "de-AT", locales["de"], false},
+		{"", "xx", locales["en"], false},
+		{"de_CH", "pl", locales["de-ch"], false},
+		{"xx", "pl", Locale{}, true},
+	}
+	for _, tt := range tests {
+		err := loadLocale(tt.tag, tt.lang)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("loadLocale(%q, %q): err = %v, want error %v", tt.tag, tt.lang, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && locale != tt.want {
+			t.Errorf("loadLocale(%q, %q) = %+v, want %+v", tt.tag, tt.lang, locale, tt.want)
+		}
+	}
+}
@@ -2,8 +2,6 @@ package main
 
 import (
 	_ "embed"
-	"encoding/json"
-	"flag"
 	"fmt"
 	"log"
 	"os"
@@ -12,7 +10,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/signintech/gopdf"
 	"github.com/spf13/cobra"
 )
 
@@ -22,30 +19,98 @@ var interFont []byte
 //go:embed "Inter/Inter Hinted for Windows/Desktop/Inter-Bold.ttf"
 var interBoldFont []byte
 
+//go:embed "Inter/Inter Hinted for Windows/Desktop/Inter-Italic.ttf"
+var interItalicFont []byte
+
 type Invoice struct {
 	Id    string `json:"id" yaml:"id"`
 	Title string `json:"title" yaml:"title"`
+	// NumberPattern, e.g. FV/{YYYY}/{MM}/{SEQ:4}, gives the invoice the next
+	// number of its sequence when no ID is set; NumberReset is year, month or
+	// never (see numbering.go).
+	NumberPattern string `json:"numberPattern" yaml:"numberPattern"`
+	NumberReset   string `json:"numberReset" yaml:"numberReset"`
 
 	Logo string `json:"logo" yaml:"logo"`
 	LogoScale float64 `json:"logoScale" yaml:"logoScale"`
-	From     string `json:"from" yaml:"from"`
-	To       string `json:"to" yaml:"to"`
+	From     Party  `json:"from" yaml:"from"`
+	To       Party  `json:"to" yaml:"to"`
+	FromVatId string `json:"fromVatId" yaml:"fromVatId"`
+	ToVatId   string `json:"toVatId" yaml:"toVatId"`
 	Date     string `json:"date" yaml:"date"`
 	SaleDate string `json:"saleDate" yaml:"saleDate"`
 	Due      string `json:"due" yaml:"due"`
+	// PaymentTerms sets the due date that many days after the issue date
+	// when no due date is given.
+	PaymentTerms int `json:"paymentTerms" yaml:"paymentTerms"`
 	BillingPeriod string `json:"billingPeriod" yaml:"billingPeriod"`
 
-	Items      []string  `json:"items" yaml:"items"`
-	Quantities []int     `json:"quantities" yaml:"quantities"`
+	Items []LineItem `json:"items" yaml:"items"`
+	// QuantityPrecision is the number of decimals shown in the QTY column;
+	// a negative value shows as many as the quantity needs (7.5, 2, 0.25).
+	QuantityPrecision int `json:"quantityPrecision" yaml:"quantityPrecision"`
+	// Quantities and Rates belong to the old form where items is a list of
+	// plain strings; resolveLineItems folds them into Items.
+	Quantities []float64 `json:"quantities" yaml:"quantities"`
 	Rates      []float64 `json:"rates" yaml:"rates"`
 
 	Tax      float64 `json:"tax" yaml:"tax"`
 	TaxName  string  `json:"taxName" yaml:"taxName"`
+	// Taxes replaces Tax/TaxName when several taxes apply together (GST+PST,
+	// state+county+city); each is simple or compound.
+	Taxes    []Tax   `json:"taxes" yaml:"taxes"`
+	// TaxTreatment is standard, reverse-charge, exempt or outside-scope; the
+	// non-standard ones zero all taxes and print a legal mention, which
+	// TaxTreatmentNote can replace (e.g. with the exact legal basis).
+	TaxTreatment     string `json:"taxTreatment" yaml:"taxTreatment"`
+	TaxTreatmentNote string `json:"taxTreatmentNote" yaml:"taxTreatmentNote"`
 	Discount float64 `json:"discount" yaml:"discount"`
+	// DiscountAmount is a fixed amount off the whole invoice, on top of the
+	// Discount fraction. DiscountOrder is the calculation model: beforeTax
+	// discounts lower the taxed net amount, afterTax ones come off the gross.
+	DiscountAmount float64 `json:"discountAmount" yaml:"discountAmount"`
+	DiscountOrder  string  `json:"discountOrder" yaml:"discountOrder"`
+	// Withholding is a rate of the net amount the buyer withholds (IRPF,
+	// ritenuta d'acconto, TDS); it is deducted after tax.
+	Withholding     float64 `json:"withholding" yaml:"withholding"`
+	WithholdingName string  `json:"withholdingName" yaml:"withholdingName"`
 	Paid     float64 `json:"paid" yaml:"paid"`
 	Currency string  `json:"currency" yaml:"currency"`
+	// AmountInWords prints the total due spelled out below it.
+	AmountInWords bool `json:"amountInWords" yaml:"amountInWords"`
+
+	// Rounding is the rounding mode for money amounts (half-up, half-even) and
+	// RoundingScope says whether tax is rounded per line or per document.
+	Rounding      string `json:"rounding" yaml:"rounding"`
+	RoundingScope string `json:"roundingScope" yaml:"roundingScope"`
 
 	Lang string `json:"lang" yaml:"lang"`
+	// Lang2 is the second language of a bilingual invoice, whose labels are
+	// printed after those of Lang, e.g. "Faktura / Invoice".
+	Lang2 string `json:"lang2" yaml:"lang2"`
+	// LangFallback is "error" to reject a language pack that needs English
+	// for missing keys, or "warn" to list every fallback key and go on.
+	LangFallback string `json:"langFallback" yaml:"langFallback"`
+	// Locale selects number, currency and date formatting (e.g. pl, de-CH);
+	// empty uses the rules of Lang.
+	Locale string `json:"locale" yaml:"locale"`
+
+	// PageSize is the paper size (A4, A5, Letter, Legal) and Orientation is
+	// portrait or landscape.
+	PageSize    string `json:"pageSize" yaml:"pageSize"`
+	Orientation string `json:"orientation" yaml:"orientation"`
+
+	// Theme is the path of a YAML/JSON theme file; empty uses the built-in theme.
+	Theme string `json:"theme" yaml:"theme"`
+
+	// Font, BoldFont and ItalicFont are TTF/OTF file paths replacing the
+	// embedded Inter faces; bold and italic default to Font when it is set.
+	Font       string `json:"font" yaml:"font"`
+	BoldFont   string `json:"boldFont" yaml:"boldFont"`
+	ItalicFont string `json:"italicFont" yaml:"italicFont"`
+	// FallbackFonts are font files tried in order for characters the fonts
+	// above lack, e.g. CJK, Arabic or Hebrew text.
+	FallbackFonts []string `json:"fallbackFonts" yaml:"fallbackFonts"`
 
 	PaymentMethod string `json:"paymentMethod" yaml:"paymentMethod"`
 	Bank          string `json:"bank" yaml:"bank"`
@@ -60,11 +125,10 @@ func DefaultInvoice() Invoice {
 		Id:         time.Now().Format("20060102"),
 		Title:      "",
 		LogoScale:  100.0,
-		Rates:      []float64{25},
-		Quantities: []int{2},
-		Items:      []string{"Paper Cranes"},
-		From:       "Project Folded, Inc.",
-		To:         "Untitled Corporation, Inc.",
+		Items:      []LineItem{{Description: "Paper Cranes", Quantity: 2, UnitPrice: 25}},
+		QuantityPrecision: -1,
+		From:       Party{Name: "Project Folded, Inc."},
+		To:         Party{Name: "Untitled Corporation, Inc."},
 		// Dates use ISO format YYYY-MM-DD
 		Date:       time.Now().Format("2006-01-02"),
 		SaleDate:   time.Now().Format("2006-01-02"),
@@ -72,10 +136,17 @@ func DefaultInvoice() Invoice {
 		BillingPeriod: "",
 		Tax:      0,
 		TaxName:  "",
+		TaxTreatment: string(TreatmentStandard),
 		Discount: 0,
+		DiscountOrder: string(DiscountBeforeTax),
 		Paid:     0,
 		Currency: "USD",
+		Rounding:      string(RoundHalfUp),
+		RoundingScope: string(ScopeLine),
 		Lang:     "en",
+		LangFallback: "error",
+		PageSize:    "A4",
+		Orientation: "portrait",
 	}
 }
 
@@ -87,41 +158,61 @@ var (
 
 // LangStrings holds all translatable strings loaded from lang/<code>.json
 type LangStrings struct {
-	Title           string `json:"_title"`
-	InvNo           string `json:"_invNo"`
-	IssueDate       string `json:"_issueDate"`
-	SaleDate        string `json:"_saleDate"`
-	DueDate         string `json:"_dueDate"`
-	BillingPeriod   string `json:"_billingPeriod"`
-	Seller          string `json:"_seller"`
-	Buyer           string `json:"_buyer"`
-	Item            string `json:"_item"`
-	Qty             string `json:"_qty"`
-	UnitNet         string `json:"_unitNet"`
-	TotalNet        string `json:"_totalNet"`
-	Tax             string `json:"_tax"`
-	NA              string `json:"_na"`
-	TotalGross      string `json:"_totalGross"`
-	Notes           string `json:"_notes"`
-	Payment         string `json:"_payment"`
-	Bank            string `json:"_bank"`
-	Swift           string `json:"_swift"`
-	AccountNo       string `json:"_accountNo"`
-	TotalNetPrice   string `json:"_totalNetPrice"`
-	Rate            string `json:"_rate"`
-	Amount          string `json:"_amount"`
-	Discount        string `json:"_discount"`
-	TotalGrossPrice string `json:"_totalGrossPrice"`
-	PaidLabel       string `json:"_paid"`
-	TotalDue        string `json:"_totalDue"`
+	Title             string `json:"_title"`
+	InvNo             string `json:"_invNo"`
+	IssueDate         string `json:"_issueDate"`
+	SaleDate          string `json:"_saleDate"`
+	DueDate           string `json:"_dueDate"`
+	BillingPeriod     string `json:"_billingPeriod"`
+	Seller            string `json:"_seller"`
+	Buyer             string `json:"_buyer"`
+	Item              string `json:"_item"`
+	Qty               string `json:"_qty"`
+	UnitNet           string `json:"_unitNet"`
+	TotalNet          string `json:"_totalNet"`
+	Tax               string `json:"_tax"`
+	NA                string `json:"_na"`
+	TotalGross        string `json:"_totalGross"`
+	Notes             string `json:"_notes"`
+	Payment           string `json:"_payment"`
+	Bank              string `json:"_bank"`
+	Swift             string `json:"_swift"`
+	AccountNo         string `json:"_accountNo"`
+	TotalNetPrice     string `json:"_totalNetPrice"`
+	Rate              string `json:"_rate"`
+	Amount            string `json:"_amount"`
+	Discount          string `json:"_discount"`
+	TotalGrossPrice   string `json:"_totalGrossPrice"`
+	PaidLabel         string `json:"_paid"`
+	TotalDue          string `json:"_totalDue"`
+	Withholding       string `json:"_withholding"`
+	VatId             string `json:"_vatId"`
+	TaxReverseCharge  string `json:"_taxReverseCharge"`
+	TaxExempt         string `json:"_taxExempt"`
+	TaxOutsideScope   string `json:"_taxOutsideScope"`
+	ReverseChargeNote string `json:"_reverseChargeNote"`
+	ExemptNote        string `json:"_exemptNote"`
+	OutsideScopeNote  string `json:"_outsideScopeNote"`
+	CarriedForward    string `json:"_carriedForward"`
+	BroughtForward    string `json:"_broughtForward"`
+	Page              string `json:"_page"`
+	AmountInWords     string `json:"_amountInWords"`
+	RegistrationNo    string `json:"_registrationNo"`
+
+	// The number words spell out the amount in words (see numberWords). They
+	// are optional and only ever taken from a pack of the same language.
+	NumberGrammar  string `json:"_numberGrammar"`
+	NumberWords    string `json:"_numberWords"`
+	NumberTens     string `json:"_numberTens"`
+	NumberHundreds string `json:"_numberHundreds"`
+	NumberScales   string `json:"_numberScales"`
+	NumberMinus    string `json:"_numberMinus"`
+	CurrencyWords  string `json:"_currencyWords"`
 }
 
 // langStrings is the currently loaded language pack used across the PDF generation.
 var langStrings LangStrings
 
-// englishLangValidated tracks whether we've already validated lang/en.json.
-var englishLangValidated bool
-
 // validateLang ensures that all required translation keys are present and non-empty.
 func validateLang(ls *LangStrings, code string) error {
 	missing := []string{}
@@ -207,57 +298,67 @@ func validateLang(ls *LangStrings, code string) error {
 	if ls.TotalDue == "" {
 		missing = append(missing, "_totalDue")
 	}
-
-	if len(missing) > 0 {
-		return fmt.Errorf("language file lang/%s.json is missing required keys: %s", code, strings.Join(missing, ", "))
+	if ls.Withholding == "" {
+		missing = append(missing, "_withholding")
 	}
-	return nil
-}
-
-// ensureEnglishLang ensures that lang/en.json exists and is complete. The app
-// will not run without a valid English language file.
-func ensureEnglishLang() error {
-	if englishLangValidated {
-		return nil
+	if ls.VatId == "" {
+		missing = append(missing, "_vatId")
 	}
-	path := filepath.Join("lang", "en.json")
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return fmt.Errorf("required language file %s is missing: %w", path, err)
+	if ls.TaxReverseCharge == "" {
+		missing = append(missing, "_taxReverseCharge")
 	}
-	var en LangStrings
-	if err := json.Unmarshal(data, &en); err != nil {
-		return fmt.Errorf("unable to parse required language file %s: %w", path, err)
+	if ls.TaxExempt == "" {
+		missing = append(missing, "_taxExempt")
 	}
-	if err := validateLang(&en, "en"); err != nil {
-		return err
+	if ls.TaxOutsideScope == "" {
+		missing = append(missing, "_taxOutsideScope")
 	}
-	englishLangValidated = true
-	return nil
-}
-
-// loadLang loads the requested language and validates that all keys are present.
-// English (lang/en.json) is always required and must be valid.
-func loadLang(code string) error {
-	if err := ensureEnglishLang(); err != nil {
-		return err
+	if ls.ReverseChargeNote == "" {
+		missing = append(missing, "_reverseChargeNote")
+	}
+	if ls.ExemptNote == "" {
+		missing = append(missing, "_exemptNote")
+	}
+	if ls.OutsideScopeNote == "" {
+		missing = append(missing, "_outsideScopeNote")
+	}
+	if ls.CarriedForward == "" {
+		missing = append(missing, "_carriedForward")
+	}
+	if ls.BroughtForward == "" {
+		missing = append(missing, "_broughtForward")
+	}
+	if ls.Page == "" {
+		missing = append(missing, "_page")
+	}
+	if ls.AmountInWords == "" {
+		missing = append(missing, "_amountInWords")
+	}
+	if ls.RegistrationNo == "" {
+		missing = append(missing, "_registrationNo")
 	}
 
-	if code == "" {
-		code = "en"
+	if len(missing) > 0 {
+		return fmt.Errorf("language %s is missing required keys: %s", code, strings.Join(missing, ", "))
 	}
+	return nil
+}
 
-	path := filepath.Join("lang", code+".json")
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return fmt.Errorf("unable to read language file %s: %w", path, err)
+// resolveDueDate sets a missing due date: PaymentTerms days after the issue
+// date, or the default of a week from today.
+func resolveDueDate(inv *Invoice) error {
+	if inv.Due != "" {
+		return nil
 	}
-	if err := json.Unmarshal(data, &langStrings); err != nil {
-		return fmt.Errorf("unable to parse language file %s: %w", path, err)
+	if inv.PaymentTerms <= 0 {
+		inv.Due = defaultInvoice.Due
+		return nil
 	}
-	if err := validateLang(&langStrings, code); err != nil {
-		return err
+	issued, err := time.Parse("2006-01-02", inv.Date)
+	if err != nil {
+		return fmt.Errorf("paymentTerms needs the issue date in YYYY-MM-DD form, got %q", inv.Date)
 	}
+	inv.Due = issued.AddDate(0, 0, inv.PaymentTerms).Format("2006-01-02")
 	return nil
 }
 
@@ -279,30 +380,127 @@ func sanitizeFilename(id string) string {
 	return id
 }
 
+// writeFileAtomic writes data to a temporary file beside path and renames it
+// over path, so a crash never leaves a half-written file behind. Each writer
+// has its own temporary file.
+func writeFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Chmod(0o644)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// lockWait is how long a command waits for another run to finish with a
+// locked file.
+const lockWait = 30 * time.Second
+
+// lockFile locks path for a load, change and save, waiting while another run
+// of invoice holds it. The lock is a system file lock on path+".lock", so a
+// run that is killed never leaves it behind. It returns the function that
+// releases the lock.
+func lockFile(path string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("unable to create directory %s: %w", filepath.Dir(path), err)
+	}
+	f, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("unable to lock %s: %w", path, err)
+	}
+	deadline := time.Now().Add(lockWait)
+	for {
+		locked, err := tryLock(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("unable to lock %s: %w", path, err)
+		}
+		if locked {
+			return func() { f.Close() }, nil
+		}
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, fmt.Errorf("%s is locked by another run of invoice", path)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
 func init() {
+	rootCmd.PersistentFlags().StringVar(&langDir, "lang-dir", "", "Directory of language packs (<code>.json) overriding or extending the built-in ones")
+
 	generateCmd.Flags().StringVar(&importPath, "import", "", "Imported file (.json/.yaml)")
+	generateCmd.Flags().StringVar(&profileName, "profile", "", "Seller profile from the config directory")
+	generateCmd.Flags().StringVar(&clientID, "client", "", "Client of the address book to bill (see invoice client)")
 	generateCmd.Flags().StringVar(&file.Id, "id", time.Now().Format("20060102"), "ID")
+	generateCmd.Flags().StringVar(&file.NumberPattern, "numberPattern", "", "Number pattern used when no ID is given, e.g. FV/{YYYY}/{MM}/{SEQ:4}")
+	generateCmd.Flags().BoolVar(&forceOutput, "force", false, "Replace the PDF if it already exists")
+	generateCmd.Flags().BoolVar(&newVersion, "newVersion", false, "Write a new version (-v2, -v3, ...) if the PDF already exists")
+	generateCmd.Flags().StringVar(&file.NumberReset, "numberReset", "", "Restart the number sequence every year, month or never (default: by the pattern)")
 	// Title defaults to empty; language file provides the visible default.
 	generateCmd.Flags().StringVar(&file.Title, "title", defaultInvoice.Title, "Title")
 
-	generateCmd.Flags().Float64SliceVarP(&file.Rates, "rate", "r", defaultInvoice.Rates, "Rates")
-	generateCmd.Flags().IntSliceVarP(&file.Quantities, "quantity", "q", defaultInvoice.Quantities, "Quantities")
-	generateCmd.Flags().StringSliceVarP(&file.Items, "item", "i", defaultInvoice.Items, "Items")
+	defaultItem := defaultInvoice.Items[0]
+	generateCmd.Flags().Float64SliceVarP(&rateFlags, "rate", "r", []float64{defaultItem.UnitPrice}, "Rates")
+	generateCmd.Flags().Float64SliceVarP(&quantityFlags, "quantity", "q", []float64{defaultItem.Quantity}, "Quantities (decimals allowed, e.g. 7.5)")
+	generateCmd.Flags().StringSliceVarP(&itemFlags, "item", "i", []string{defaultItem.Description}, "Items")
+	generateCmd.Flags().IntVar(&file.QuantityPrecision, "quantityPrecision", defaultInvoice.QuantityPrecision, "Decimals shown for quantities (-1 shows as many as needed)")
 
 	generateCmd.Flags().StringVarP(&file.Logo, "logo", "l", defaultInvoice.Logo, "Company logo")
-	generateCmd.Flags().StringVarP(&file.From, "from", "f", defaultInvoice.From, "Issuing company")
-	generateCmd.Flags().StringVarP(&file.To, "to", "t", defaultInvoice.To, "Recipient company")
+	// --from and --to take the plain-string form of a party; objects come from --import.
+	file.From, file.To = defaultInvoice.From, defaultInvoice.To
+	generateCmd.Flags().VarP(&file.From, "from", "f", "Issuing company")
+	generateCmd.Flags().VarP(&file.To, "to", "t", "Recipient company")
+	generateCmd.Flags().StringVar(&file.FromVatId, "fromVatId", "", "Seller VAT ID")
+	generateCmd.Flags().StringVar(&file.ToVatId, "toVatId", "", "Buyer VAT ID")
 	generateCmd.Flags().StringVar(&file.Date, "date", defaultInvoice.Date, "Issue date")
 	generateCmd.Flags().StringVar(&file.SaleDate, "saleDate", defaultInvoice.SaleDate, "Sale date (defaults to issue date)")
 	generateCmd.Flags().StringVar(&file.Due, "due", defaultInvoice.Due, "Payment due date")
+	generateCmd.Flags().IntVar(&file.PaymentTerms, "paymentTerms", 0, "Days from issue date to due date, used when --due isn't given")
 	generateCmd.Flags().StringVar(&file.BillingPeriod, "billingPeriod", defaultInvoice.BillingPeriod, "Billing period (optional, shown below due date)")
 
 	generateCmd.Flags().Float64Var(&file.Tax, "tax", defaultInvoice.Tax, "Tax")
 	generateCmd.Flags().StringVar(&file.TaxName, "taxName", defaultInvoice.TaxName, "Tax label (e.g. VAT)")
+	generateCmd.Flags().StringVar(&file.TaxTreatment, "taxTreatment", defaultInvoice.TaxTreatment, "Tax treatment (standard, reverse-charge, exempt, outside-scope)")
+	generateCmd.Flags().StringVar(&file.TaxTreatmentNote, "taxTreatmentNote", "", "Legal mention replacing the language file one")
 	generateCmd.Flags().Float64VarP(&file.Discount, "discount", "d", defaultInvoice.Discount, "Discount")
+	generateCmd.Flags().Float64Var(&file.DiscountAmount, "discountAmount", defaultInvoice.DiscountAmount, "Fixed discount amount")
+	generateCmd.Flags().StringVar(&file.DiscountOrder, "discountOrder", defaultInvoice.DiscountOrder, "Apply discounts before or after tax (beforeTax, afterTax)")
+	generateCmd.Flags().Float64Var(&file.Withholding, "withholding", defaultInvoice.Withholding, "Withholding tax rate deducted from the total (e.g. 0.15)")
+	generateCmd.Flags().StringVar(&file.WithholdingName, "withholdingName", defaultInvoice.WithholdingName, "Withholding tax label (e.g. IRPF)")
 	generateCmd.Flags().Float64Var(&file.Paid, "paid", defaultInvoice.Paid, "Amount already paid")
+	generateCmd.Flags().BoolVar(&file.AmountInWords, "amountInWords", false, "Print the total due in words (languages with number words, e.g. en, pl)")
 	generateCmd.Flags().StringVarP(&file.Currency, "currency", "c", defaultInvoice.Currency, "Currency")
+	generateCmd.Flags().StringVar(&file.Rounding, "rounding", defaultInvoice.Rounding, "Rounding mode for amounts (half-up, half-even)")
+	generateCmd.Flags().StringVar(&file.RoundingScope, "roundingScope", defaultInvoice.RoundingScope, "Round tax per line or per document (line, document)")
 	generateCmd.Flags().StringVar(&file.Lang, "lang", defaultInvoice.Lang, "Language code (e.g. en)")
+	generateCmd.Flags().StringVar(&file.Lang2, "lang2", "", "Second language for bilingual labels (e.g. en)")
+	generateCmd.Flags().StringVar(&file.LangFallback, "langFallback", defaultInvoice.LangFallback, "Keys missing from the language pack: error, or warn and use English")
+	generateCmd.Flags().StringVar(&file.Locale, "locale", "", "Number, currency and date format (e.g. pl, de, en-US); defaults to --lang")
+	generateCmd.Flags().StringVar(&file.PageSize, "pageSize", defaultInvoice.PageSize, "Page size (A4, A5, Letter, Legal)")
+	generateCmd.Flags().StringVar(&file.Orientation, "orientation", defaultInvoice.Orientation, "Page orientation (portrait, landscape)")
+	generateCmd.Flags().StringVar(&file.Theme, "theme", "", "Theme file with colors, font sizes and spacing (YAML/JSON)")
+	generateCmd.Flags().StringVar(&file.Font, "font", "", "Regular font file (TTF/OTF) replacing Inter")
+	generateCmd.Flags().StringVar(&file.BoldFont, "boldFont", "", "Bold font file (TTF/OTF), defaults to --font")
+	generateCmd.Flags().StringVar(&file.ItalicFont, "italicFont", "", "Italic font file (TTF/OTF), defaults to --font")
+	generateCmd.Flags().StringArrayVar(&file.FallbackFonts, "fallbackFonts", nil, "Fallback font file for missing glyphs, e.g. CJK or Arabic (repeat for a chain)")
 
 	generateCmd.Flags().StringVar(&file.PaymentMethod, "paymentMethod", "", "Method of payment")
 	generateCmd.Flags().StringVar(&file.Bank, "bank", "", "Bank")
@@ -311,8 +509,6 @@ func init() {
 
 	generateCmd.Flags().StringVarP(&file.Note, "note", "n", "", "Note")
 	generateCmd.Flags().Float64Var(&file.LogoScale, "logoScale", defaultInvoice.LogoScale, "Logo scale (default 100)")
-
-	flag.Parse()
 }
 
 var rootCmd = &cobra.Command{
@@ -327,71 +523,87 @@ var generateCmd = &cobra.Command{
 	Long:  `Generate an invoice`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 
+		if profileName != "" {
+			if err := applyProfile(&file, profileName, cmd.Flags()); err != nil {
+				return err
+			}
+		}
+		if clientID != "" {
+			if err := applyClient(&file, clientID, cmd.Flags()); err != nil {
+				return err
+			}
+		}
+		// the due date is worked out once the issue date and terms are known,
+		// unless --due or the imported file sets it
+		if !cmd.Flags().Changed("due") {
+			file.Due = ""
+		}
+		// likewise the ID, which may come from the number pattern
+		if !cmd.Flags().Changed("id") {
+			file.Id = ""
+		}
 		if importPath != "" {
 			err := importData(importPath, &file, cmd.Flags())
 			if err != nil {
 				return err
 			}
+			if err := resolveLineItems(&file); err != nil {
+				return err
+			}
+		}
+		if err := applyItemFlags(&file, cmd.Flags(), importPath != ""); err != nil {
+			return err
+		}
+		if err := resolveParties(&file); err != nil {
+			return err
+		}
+		if err := resolveDueDate(&file); err != nil {
+			return err
 		}
 
 		// Load language strings based on requested language code
-		if err := loadLang(file.Lang); err != nil {
+		if err := loadLang(file.Lang, file.Lang2, file.LangFallback); err != nil {
+			return err
+		}
+		if err := loadLocale(file.Locale, file.Lang); err != nil {
 			return err
 		}
 
-		pdf := gopdf.GoPdf{}
-		pdf.Start(gopdf.Config{
-			PageSize: *gopdf.PageSizeA4,
-		})
-		pdf.SetMargins(40, 40, 40, 40)
-		pdf.AddPage()
-		err := pdf.AddTTFFontData("Inter", interFont)
+		totals, err := computeTotals(&file)
 		if err != nil {
 			return err
 		}
+		if file.AmountInWords {
+			if _, err := amountInWords(totals.Due); err != nil {
+				return err
+			}
+		}
 
-		err = pdf.AddTTFFontData("Inter-Bold", interBoldFont)
+		layout, err = newLayout(file.PageSize, file.Orientation)
 		if err != nil {
 			return err
 		}
+		if err := loadTheme(file.Theme); err != nil {
+			return err
+		}
+		if err := loadFonts(file.Font, file.BoldFont, file.ItalicFont, defaultFallbackFonts(file.FallbackFonts, file.Lang, file.Lang2)); err != nil {
+			return err
+		}
 
-		writeLogo(&pdf, file.Logo, file.LogoScale)
-		writeHeaderBlock(&pdf, file.Title, file.Id, file.Date, file.SaleDate, file.Due, file.BillingPeriod)
-		writeSellerBuyerColumns(&pdf, file.From, file.To)
-		writeHeaderRow(&pdf)
-		writeDivider(&pdf)      // divider before items table
-		subtotal := 0.0
-		for i := range file.Items {
-			// Determine quantity for this item; default is 1 if not provided.
-			q := 1
-			if len(file.Quantities) > i {
-				q = file.Quantities[i]
-			}
-			// If quantity is explicitly set to 0, skip this item entirely.
-			if q == 0 {
-				continue
-			}
-
-			// Determine rate for this item; default is 0 if not provided.
-			r := 0.0
-			if len(file.Rates) > i {
-				r = file.Rates[i]
+		var reservation *numberReservation
+		if file.Id == "" && file.NumberPattern != "" {
+			reservation, err = reserveNumber(&file, profileName)
+			if err != nil {
+				return err
 			}
-
-			writeRow(&pdf, file.Items[i], q, r)
-			subtotal += float64(q) * r
-		}
-		//writeDivider(&pdf) // divider after items table
-		pdf.Br(itemsToNotesGap)
-		sectionY := pdf.GetY()
-		if file.Note != "" || file.PaymentMethod != "" || file.Bank != "" || file.Swift != "" || file.AccountNo != "" {
-			writeNotes(&pdf, file.Note, file.PaymentMethod, file.Bank, file.Swift, file.AccountNo)
+			defer reservation.release()
+		} else if file.Id == "" {
+			file.Id = defaultInvoice.Id
 		}
-		writeTotals(&pdf, sectionY, subtotal, subtotal*file.Tax, subtotal*file.Discount)
-		writeFooter(&pdf, file.Id)
 
 		// Always write into ./output directory, filename based on sanitized invoice ID
-		// plus the language code, e.g. 1-02-2026-en.pdf.
+		// plus the language code(s), e.g. 1-02-2026-en.pdf or 1-02-2026-pl-en.pdf.
+		// An existing file is only replaced with --force (see issued.go).
 		outDir := "output"
 		if err := os.MkdirAll(outDir, 0o755); err != nil {
 			return fmt.Errorf("unable to create output directory %s: %w", outDir, err)
@@ -401,15 +613,40 @@ var generateCmd = &cobra.Command{
 		if langCode == "" {
 			langCode = "en"
 		}
+		if file.Lang2 != "" {
+			langCode += "-" + file.Lang2
+		}
 		langCode = strings.ToLower(langCode)
 		filename := fmt.Sprintf("%s-%s.pdf", safeID, langCode)
-		outputPath := filepath.Join(outDir, filename)
+		release, err := lockIssued(outDir)
+		if err != nil {
+			return err
+		}
+		defer release()
+		outputPath, err := checkOutput(filepath.Join(outDir, filename), totals)
+		if err != nil {
+			return err
+		}
+
+		pdf, err := renderInvoice(totals)
+		if err != nil {
+			return err
+		}
 
 		err = pdf.WritePdf(outputPath)
 		if err != nil {
 			return err
 		}
 
+		if reservation != nil {
+			if err := reservation.commit(); err != nil {
+				return err
+			}
+		}
+		if err := recordIssued(outputPath, totals); err != nil {
+			return err
+		}
+
 		fmt.Printf("Generated %s\n", outputPath)
 
 		return nil
@@ -418,6 +655,9 @@ var generateCmd = &cobra.Command{
 
 func main() {
 	rootCmd.AddCommand(generateCmd)
+	rootCmd.AddCommand(langCmd)
+	rootCmd.AddCommand(clientCmd)
+	rootCmd.AddCommand(numberCmd)
 	err := rootCmd.Execute()
 	if err != nil {
 		log.Fatal(err)
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// Number resets: the sequence of a pattern starts again from 1 every year,
+// every month or never.
+const (
+	ResetYear  = "year"
+	ResetMonth = "month"
+	ResetNever = "never"
+)
+
+// numberTokenPattern matches the placeholders of a number pattern: {YYYY},
+// {YY}, {MM} and {SEQ}, optionally zero-padded as {SEQ:4}.
+var numberTokenPattern = regexp.MustCompile(`\{([A-Z]+)(?::(\d+))?\}`)
+
+// numberBook records the invoice numbers handed out from patterns. It is kept
+// as numbers.yaml in the config directory.
+type numberBook struct {
+	// Counters holds the last sequence number of each profile and period,
+	// keyed "<profile> <period>".
+	Counters map[string]int `yaml:"counters"`
+	Issued   []issuedNumber `yaml:"issued"`
+}
+
+// issuedNumber is an invoice number handed out by generate.
+type issuedNumber struct {
+	Number   string `yaml:"number"`
+	Counter  string `yaml:"counter"`
+	Sequence int    `yaml:"sequence"`
+	Date     string `yaml:"date"`
+}
+
+// numberReservation is a number taken from the book. The book stays locked
+// until release, and the number is only recorded by commit, so an invoice
+// that fails to generate leaves no gap.
+type numberReservation struct {
+	path    string
+	book    numberBook
+	issued  issuedNumber
+	release func()
+}
+
+// numbersPath returns the number book file in the config directory.
+func numbersPath() (string, error) {
+	dir := configDir()
+	if dir == "" {
+		return "", fmt.Errorf("no config directory on this system for invoice numbers")
+	}
+	return filepath.Join(dir, "numbers.yaml"), nil
+}
+
+// loadNumbers reads the number book. A missing file is an empty book.
+func loadNumbers(path string) (numberBook, error) {
+	book := numberBook{Counters: map[string]int{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return book, nil
+	}
+	if err != nil {
+		return book, fmt.Errorf("unable to read invoice numbers %s: %w", path, err)
+	}
+	if err := yaml.Unmarshal(data, &book); err != nil {
+		return book, fmt.Errorf("unable to parse invoice numbers %s: %w", path, err)
+	}
+	if book.Counters == nil {
+		book.Counters = map[string]int{}
+	}
+	return book, nil
+}
+
+// saveNumbers writes the number book.
+func saveNumbers(path string, book numberBook) error {
+	data, err := yaml.Marshal(book)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return fmt.Errorf("unable to write invoice numbers %s: %w", path, err)
+	}
+	return nil
+}
+
+// numberReset returns the reset of a pattern: the one given, or else the
+// shortest period the pattern names. A reset shorter than the pattern's
+// periods would repeat numbers, so it is an error.
+func numberReset(pattern, reset string) (string, error) {
+	hasMonth := strings.Contains(pattern, "{MM}")
+	hasYear := strings.Contains(pattern, "{YYYY}") || strings.Contains(pattern, "{YY}")
+	switch reset {
+	case "":
+		if hasMonth && hasYear {
+			return ResetMonth, nil
+		}
+		if hasYear {
+			return ResetYear, nil
+		}
+		return ResetNever, nil
+	case ResetNever:
+	case ResetYear:
+		if !hasYear {
+			return "", fmt.Errorf("numberReset %s needs {YYYY} or {YY} in numberPattern, or numbers would repeat", reset)
+		}
+	case ResetMonth:
+		if !hasYear || !hasMonth {
+			return "", fmt.Errorf("numberReset %s needs {MM} and {YYYY} or {YY} in numberPattern, or numbers would repeat", reset)
+		}
+	default:
+		return "", fmt.Errorf("unknown numberReset %q (use %s, %s or %s)", reset, ResetYear, ResetMonth, ResetNever)
+	}
+	return reset, nil
+}
+
+// formatNumber fills in the placeholders of a pattern.
+func formatNumber(pattern string, date time.Time, seq int) (string, error) {
+	seqs := 0
+	var bad error
+	number := numberTokenPattern.ReplaceAllStringFunc(pattern, func(token string) string {
+		m := numberTokenPattern.FindStringSubmatch(token)
+		switch {
+		case m[1] == "SEQ":
+			seqs++
+			width, _ := strconv.Atoi(m[2])
+			return fmt.Sprintf("%0*d", width, seq)
+		case m[2] != "":
+			bad = fmt.Errorf("numberPattern: only {SEQ} takes a width, not %s", token)
+		case m[1] == "YYYY":
+			return date.Format("2006")
+		case m[1] == "YY":
+			return date.Format("06")
+		case m[1] == "MM":
+			return date.Format("01")
+		default:
+			bad = fmt.Errorf("numberPattern: unknown placeholder %s (use {YYYY}, {YY}, {MM} and {SEQ})", token)
+		}
+		return token
+	})
+	if bad != nil {
+		return "", bad
+	}
+	if seqs != 1 {
+		return "", fmt.Errorf("numberPattern %q needs exactly one {SEQ}", pattern)
+	}
+	return number, nil
+}
+
+// numberCounter returns the key of the counter that numbers an invoice of a
+// profile issued on date: "<profile> <period>", where the period is the year,
+// the month or "all".
+func numberCounter(profile, reset string, date time.Time) string {
+	if profile == "" {
+		profile = "default"
+	}
+	period := "all"
+	switch reset {
+	case ResetYear:
+		period = date.Format("2006")
+	case ResetMonth:
+		period = date.Format("2006-01")
+	}
+	return profile + " " + period
+}
+
+// reserveNumber gives an invoice the next number of its pattern. The counter
+// is kept per profile and per period of the reset.
+func reserveNumber(inv *Invoice, profile string) (*numberReservation, error) {
+	reset, err := numberReset(inv.NumberPattern, inv.NumberReset)
+	if err != nil {
+		return nil, err
+	}
+	date, err := time.Parse("2006-01-02", inv.Date)
+	if err != nil {
+		return nil, fmt.Errorf("numberPattern needs the issue date in YYYY-MM-DD form, got %q", inv.Date)
+	}
+	// check the pattern before taking the lock
+	if _, err := formatNumber(inv.NumberPattern, date, 1); err != nil {
+		return nil, err
+	}
+
+	path, err := numbersPath()
+	if err != nil {
+		return nil, err
+	}
+	release, err := lockFile(path)
+	if err != nil {
+		return nil, err
+	}
+	book, err := loadNumbers(path)
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	counter := numberCounter(profile, reset, date)
+	seq := book.Counters[counter] + 1
+	number, _ := formatNumber(inv.NumberPattern, date, seq)
+	for _, issued := range book.Issued {
+		if issued.Number == number {
+			release()
+			return nil, fmt.Errorf("invoice number %s was already issued on %s (counter %q); check numberPattern and numberReset", number, issued.Date, issued.Counter)
+		}
+	}
+
+	inv.Id = number
+	return &numberReservation{
+		path:    path,
+		book:    book,
+		issued:  issuedNumber{Number: number, Counter: counter, Sequence: seq, Date: inv.Date},
+		release: release,
+	}, nil
+}
+
+// commit records the reserved number in the book.
+func (r *numberReservation) commit() error {
+	r.book.Counters[r.issued.Counter] = r.issued.Sequence
+	r.book.Issued = append(r.book.Issued, r.issued)
+	return saveNumbers(r.path, r.book)
+}
+
+// checkNumbers returns the problems of a number book: numbers issued twice,
+// and sequence numbers of a counter that were skipped.
+func checkNumbers(book numberBook) []string {
+	problems := []string{}
+	seen := map[string]int{}
+	sequences := map[string][]int{}
+	for _, issued := range book.Issued {
+		seen[issued.Number]++
+		if seen[issued.Number] == 2 {
+			problems = append(problems, fmt.Sprintf("%s was issued more than once", issued.Number))
+		}
+		sequences[issued.Counter] = append(sequences[issued.Counter], issued.Sequence)
+	}
+	counters := []string{}
+	for counter := range sequences {
+		counters = append(counters, counter)
+	}
+	for counter := range book.Counters {
+		if _, ok := sequences[counter]; !ok {
+			counters = append(counters, counter)
+		}
+	}
+	sort.Strings(counters)
+	for _, counter := range counters {
+		used := map[int]bool{}
+		for _, seq := range sequences[counter] {
+			used[seq] = true
+		}
+		missing := []string{}
+		for seq := 1; seq <= book.Counters[counter]; seq++ {
+			if !used[seq] {
+				missing = append(missing, strconv.Itoa(seq))
+			}
+		}
+		if len(missing) > 0 {
+			problems = append(problems, fmt.Sprintf("counter %q skips %s", counter, strings.Join(missing, ", ")))
+		}
+	}
+	return problems
+}
+
+var numberCmd = &cobra.Command{
+	Use:   "number",
+	Short: "List and check the invoice numbers handed out",
+	Long: `List and check the invoice numbers handed out by generate from a
+numberPattern.`,
+}
+
+var numberListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the invoice numbers handed out",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := numbersPath()
+		if err != nil {
+			return err
+		}
+		book, err := loadNumbers(path)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NUMBER\tDATE\tCOUNTER\tSEQ")
+		for _, issued := range book.Issued {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\n", issued.Number, issued.Date, issued.Counter, issued.Sequence)
+		}
+		return w.Flush()
+	},
+}
+
+var numberCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Check the invoice numbers for gaps and duplicates",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := numbersPath()
+		if err != nil {
+			return err
+		}
+		book, err := loadNumbers(path)
+		if err != nil {
+			return err
+		}
+		if problems := checkNumbers(book); len(problems) > 0 {
+			return fmt.Errorf("invoice numbers have problems:\n  %s", strings.Join(problems, "\n  "))
+		}
+		fmt.Printf("%d invoice numbers, no gaps or duplicates\n", len(book.Issued))
+		return nil
+	},
+}
+
+func init() {
+	numberCmd.AddCommand(numberListCmd, numberCheckCmd)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatNumber(t *testing.T) {
+	date := time.Date(2026, 3, 7, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		pattern string
+		seq     int
+		want    string
+		wantErr bool
+	}{
+		{"FV/{YYYY}/{MM}/{SEQ:4}", 7, "FV/2026/03/0007", false},
+		{"FV/{YYYY}/{MM}/{SEQ:4}", 12345, "FV/2026/03/12345", false},
+		{"{YY}-{SEQ}", 42, "26-42", false},
+		{"INV{SEQ:2}", 3, "INV03", false},
+		{"{YYYY}", 1, "", true},
+		{"{SEQ}-{SEQ}", 1, "", true},
+		{"{DD}-{SEQ}", 1, "", true},
+		{"{YYYY:2}-{SEQ}", 1, "", true},
+	}
+	for _, tt := range tests {
+		got, err := formatNumber(tt.pattern, date, tt.seq)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("formatNumber(%q): err = %v, want error %v", tt.pattern, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("formatNumber(%q, %d) = %q, want %q", tt.pattern, tt.seq, got, tt.want)
+		}
+	}
+}
+
+func TestNumberReset(t *testing.T) {
+	tests := []struct {
+		pattern, reset, want string
+		wantErr              bool
+	}{
+		{"FV/{YYYY}/{MM}/{SEQ}", "", ResetMonth, false},
+		{"FV/{YYYY}/{SEQ}", "", ResetYear, false},
+		{"FV/{YY}/{SEQ}", "", ResetYear, false},
+		{"FV/{SEQ}", "", ResetNever, false},
+		{"FV/{YYYY}/{MM}/{SEQ}", ResetYear, ResetYear, false},
+		{"FV/{YYYY}/{SEQ}", ResetNever, ResetNever, false},
+		{"FV/{YYYY}/{SEQ}", ResetMonth, "", true},
+		{"FV/{MM}/{SEQ}", ResetMonth, "", true},
+		{"FV/{SEQ}", ResetYear, "", true},
+		{"FV/{SEQ}", "weekly", "", true},
+	}
+	for _, tt := range tests {
+		got, err := numberReset(tt.pattern, tt.reset)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("numberReset(%q, %q): err = %v, want error %v", tt.pattern, tt.reset, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("numberReset(%q, %q) = %q, want %q", tt.pattern, tt.reset, got, tt.want)
+		}
+	}
+}
+
+func TestNumberCounter(t *testing.T) {
+	date := time.Date(2026, 11, 2, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		profile, reset, want string
+	}{
+		{"", ResetMonth, "default 2026-11"},
+		{"kawa", ResetMonth, "kawa 2026-11"},
+		{"kawa", ResetYear, "kawa 2026"},
+		{"kawa", ResetNever, "kawa all"},
+	}
+	for _, tt := range tests {
+		if got := numberCounter(tt.profile, tt.reset, date); got != tt.want {
+			t.Errorf("numberCounter(%q, %q) = %q, want %q", tt.profile, tt.reset, got, tt.want)
+		}
+	}
+}
+
+func TestCheckNumbers(t *testing.T) {
+	book := numberBook{
+		Counters: map[string]int{"default 2026-10": 5, "kawa all": 2, "old all": 1},
+		Issued: []issuedNumber{
+			{Number: "FV/2026/10/0001", Counter: "default 2026-10", Sequence: 1},
+			{Number: "FV/2026/10/0002", Counter: "default 2026-10", Sequence: 2},
+			{Number: "FV/2026/10/0005", Counter: "default 2026-10", Sequence: 5},
+			{Number: "K1", Counter: "kawa all", Sequence: 1},
+			{Number: "K1", Counter: "kawa all", Sequence: 2},
+		},
+	}
+	want := []string{
+		"K1 was issued more than once",
+		`counter "default 2026-10" skips 3, 4`,
+		`counter "old all" skips 1`,
+	}
+	got := checkNumbers(book)
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("checkNumbers = %q, want %q", got, want)
+	}
+
+	clean := numberBook{
+		Counters: map[string]int{"default all": 2},
+		Issued: []issuedNumber{
+			{Number: "1", Counter: "default all", Sequence: 1},
+			{Number: "2", Counter: "default all", Sequence: 2},
+		},
+	}
+	if got := checkNumbers(clean); len(got) != 0 {
+		t.Errorf("checkNumbers of a clean book = %q", got)
+	}
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Party is the seller or the buyer of an invoice. It is given either as an
+// object or, as before, as a plain string whose lines (separated by newlines
+// or a literal `\n`) are the name and then the address.
+type Party struct {
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// Address holds the street lines; PostalCode, City and Region are placed
+	// after them as the post of Country writes them.
+	Address    []string `json:"address,omitempty" yaml:"address,omitempty"`
+	PostalCode string   `json:"postalCode,omitempty" yaml:"postalCode,omitempty"`
+	City       string   `json:"city,omitempty" yaml:"city,omitempty"`
+	Region     string   `json:"region,omitempty" yaml:"region,omitempty"`
+	// Country is an ISO 3166-1 alpha-2 code such as PL or DE.
+	Country string `json:"country,omitempty" yaml:"country,omitempty"`
+
+	// VatId is printed with the language file's VAT ID label and used like
+	// fromVatId/toVatId; TaxIds are further identifiers, e.g. an EIN.
+	VatId              string  `json:"vatId,omitempty" yaml:"vatId,omitempty"`
+	TaxIds             []TaxId `json:"taxIds,omitempty" yaml:"taxIds,omitempty"`
+	RegistrationNumber string  `json:"registrationNumber,omitempty" yaml:"registrationNumber,omitempty"`
+	Email              string  `json:"email,omitempty" yaml:"email,omitempty"`
+	Phone              string  `json:"phone,omitempty" yaml:"phone,omitempty"`
+}
+
+// TaxId is a tax identifier of a party other than its VAT ID, printed as
+// "Type: Value".
+type TaxId struct {
+	Type  string `json:"type" yaml:"type"`
+	Value string `json:"value" yaml:"value"`
+}
+
+// partyFields is Party without its methods, used to decode objects without
+// recursing into UnmarshalJSON/UnmarshalYAML.
+type partyFields Party
+
+// partyFromText converts the plain-string form.
+func partyFromText(text string) Party {
+	lines := strings.Split(strings.ReplaceAll(text, `\n`, "\n"), "\n")
+	return Party{Name: lines[0], Address: lines[1:]}
+}
+
+// UnmarshalJSON accepts either a plain string (old form) or a party object.
+func (p *Party) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*p = partyFromText(text)
+		return nil
+	}
+	var fields partyFields
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	*p = Party(fields)
+	return nil
+}
+
+// UnmarshalYAML mirrors UnmarshalJSON for YAML input.
+func (p *Party) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*p = partyFromText(value.Value)
+		return nil
+	}
+	var fields partyFields
+	if err := value.Decode(&fields); err != nil {
+		return err
+	}
+	*p = Party(fields)
+	return nil
+}
+
+// String, Set and Type let --from and --to take the plain-string form.
+func (p *Party) String() string {
+	return strings.Join(append([]string{p.Name}, p.Address...), `\n`)
+}
+
+func (p *Party) Set(text string) error {
+	*p = partyFromText(text)
+	return nil
+}
+
+func (p *Party) Type() string {
+	return "string"
+}
+
+var (
+	countryCodePattern = regexp.MustCompile(`^[A-Z]{2}$`)
+	emailPattern       = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+)
+
+// resolveParties checks the seller and buyer and takes their VAT IDs for
+// fromVatId and toVatId unless those are set.
+func resolveParties(inv *Invoice) error {
+	if err := checkParty("from", &inv.From); err != nil {
+		return err
+	}
+	if err := checkParty("to", &inv.To); err != nil {
+		return err
+	}
+	if inv.FromVatId == "" {
+		inv.FromVatId = inv.From.VatId
+	}
+	if inv.ToVatId == "" {
+		inv.ToVatId = inv.To.VatId
+	}
+	return nil
+}
+
+// checkParty validates the details of a party and upper-cases its country
+// code. role names the party in errors.
+func checkParty(role string, p *Party) error {
+	// an empty party prints nothing, anything else needs a name
+	if strings.TrimSpace(p.Name) == "" && len(partyLines(*p, "")) > 1 {
+		return fmt.Errorf("%s: a name is required", role)
+	}
+	country := strings.ToUpper(strings.TrimSpace(p.Country))
+	if country != "" && !countryCodePattern.MatchString(country) {
+		return fmt.Errorf("%s: country %q is not a two-letter ISO 3166 code (e.g. PL, DE, US)", role, p.Country)
+	}
+	p.Country = country
+	if p.Email != "" && !emailPattern.MatchString(p.Email) {
+		return fmt.Errorf("%s: %q is not an email address", role, p.Email)
+	}
+	for _, id := range p.TaxIds {
+		if id.Type == "" || id.Value == "" {
+			return fmt.Errorf("%s: every tax ID needs a type and a value", role)
+		}
+	}
+	return nil
+}
+
+// partyLines returns the lines printed for a party: its name, its postal
+// address in the order used in its country, then its identifiers and
+// contact details. vatId is the VAT ID to print.
+func partyLines(p Party, vatId string) []string {
+	lines := append([]string{p.Name}, p.Address...)
+	lines = append(lines, postalLines(p)...)
+	if p.Country != "" {
+		lines = append(lines, countryName(p.Country))
+	}
+	if vatId != "" {
+		lines = append(lines, langStrings.VatId+": "+vatId)
+	}
+	for _, id := range p.TaxIds {
+		lines = append(lines, id.Type+": "+id.Value)
+	}
+	if p.RegistrationNumber != "" {
+		lines = append(lines, langStrings.RegistrationNo+": "+p.RegistrationNumber)
+	}
+	for _, contact := range []string{p.Email, p.Phone} {
+		if contact != "" {
+			lines = append(lines, contact)
+		}
+	}
+	return lines
+}
+
+// postalLines places the postal code, city and region: "City, Region 12345"
+// in North America and Australia, the postcode on a line of its own in the
+// UK and Ireland, and "12345 City" elsewhere.
+func postalLines(p Party) []string {
+	join := func(parts ...string) string {
+		kept := []string{}
+		for _, part := range parts {
+			if part != "" {
+				kept = append(kept, part)
+			}
+		}
+		return strings.Join(kept, " ")
+	}
+	var lines []string
+	switch p.Country {
+	case "US", "CA", "AU":
+		city := p.City
+		if city != "" && (p.Region != "" || p.PostalCode != "") {
+			city += ","
+		}
+		lines = []string{join(city, p.Region, p.PostalCode)}
+	case "GB", "IE":
+		lines = []string{p.City, p.Region, p.PostalCode}
+	default:
+		lines = []string{join(p.PostalCode, p.City), p.Region}
+	}
+	kept := []string{}
+	for _, line := range lines {
+		if line != "" {
+			kept = append(kept, line)
+		}
+	}
+	return kept
+}
+
+// countryNames are the names of common countries in the languages with a
+// built-in pack, printed on the address's country line.
+var countryNames = map[string]map[string]string{
+	"en": {
+		"AE": "United Arab Emirates", "AT": "Austria", "AU": "Australia", "BE": "Belgium",
+		"BG": "Bulgaria", "BR": "Brazil", "CA": "Canada", "CH": "Switzerland",
+		"CN": "China", "CY": "Cyprus", "CZ": "Czechia", "DE": "Germany",
+		"DK": "Denmark", "EE": "Estonia", "EG": "Egypt", "ES": "Spain",
+		"FI": "Finland", "FR": "France", "GB": "United Kingdom", "GR": "Greece",
+		"HR": "Croatia", "HU": "Hungary", "IE": "Ireland", "IL": "Israel",
+		"IN": "India", "IT": "Italy", "JP": "Japan", "LT": "Lithuania",
+		"LU": "Luxembourg", "LV": "Latvia", "MT": "Malta", "NL": "Netherlands",
+		"NO": "Norway", "NZ": "New Zealand", "PL": "Poland", "PT": "Portugal",
+		"RO": "Romania", "SA": "Saudi Arabia", "SE": "Sweden", "SG": "Singapore",
+		"SI": "Slovenia", "SK": "Slovakia", "UA": "Ukraine", "US": "United States",
+		"ZA": "South Africa",
+	},
+	"pl": {
+		"AE": "Zjednoczone Emiraty Arabskie", "AT": "Austria", "AU": "Australia", "BE": "Belgia",
+		"BG": "Bułgaria", "BR": "Brazylia", "CA": "Kanada", "CH": "Szwajcaria",
+		"CN": "Chiny", "CY": "Cypr", "CZ": "Czechy", "DE": "Niemcy",
+		"DK": "Dania", "EE": "Estonia", "EG": "Egipt", "ES": "Hiszpania",
+		"FI": "Finlandia", "FR": "Francja", "GB": "Wielka Brytania", "GR": "Grecja",
+		"HR": "Chorwacja", "HU": "Węgry", "IE": "Irlandia", "IL": "Izrael",
+		"IN": "Indie", "IT": "Włochy", "JP": "Japonia", "LT": "Litwa",
+		"LU": "Luksemburg", "LV": "Łotwa", "MT": "Malta", "NL": "Holandia",
+		"NO": "Norwegia", "NZ": "Nowa Zelandia", "PL": "Polska", "PT": "Portugalia",
+		"RO": "Rumunia", "SA": "Arabia Saudyjska", "SE": "Szwecja", "SG": "Singapur",
+		"SI": "Słowenia", "SK": "Słowacja", "UA": "Ukraina", "US": "Stany Zjednoczone",
+		"ZA": "Republika Południowej Afryki",
+	},
+	"ar": {
+		"AE": "الإمارات العربية المتحدة", "AT": "النمسا", "AU": "أستراليا", "BE": "بلجيكا",
+		"BG": "بلغاريا", "BR": "البرازيل", "CA": "كندا", "CH": "سويسرا",
+		"CN": "الصين", "CY": "قبرص", "CZ": "التشيك", "DE": "ألمانيا",
+		"DK": "الدنمارك", "EE": "إستونيا", "EG": "مصر", "ES": "إسبانيا",
+		"FI": "فنلندا", "FR": "فرنسا", "GB": "المملكة المتحدة", "GR": "اليونان",
+		"HR": "كرواتيا", "HU": "المجر", "IE": "أيرلندا", "IL": "إسرائيل",
+		"IN": "الهند", "IT": "إيطاليا", "JP": "اليابان", "LT": "ليتوانيا",
+		"LU": "لوكسمبورغ", "LV": "لاتفيا", "MT": "مالطا", "NL": "هولندا",
+		"NO": "النرويج", "NZ": "نيوزيلندا", "PL": "بولندا", "PT": "البرتغال",
+		"RO": "رومانيا", "SA": "السعودية", "SE": "السويد", "SG": "سنغافورة",
+		"SI": "سلوفينيا", "SK": "سلوفاكيا", "UA": "أوكرانيا", "US": "الولايات المتحدة",
+		"ZA": "جنوب أفريقيا",
+	},
+}
+
+// countryName returns the name of a country in the languages of the invoice,
+// e.g. "Niemcy / Germany" on a bilingual one. Each language takes the name
+// from its fallback chain, but never from another language; codes without a
+// name are printed as they are.
+func countryName(code string) string {
+	names := []string{}
+	for _, lang := range invoiceLangs {
+		name := code
+		for _, tag := range langChain(lang) {
+			if baseLang(tag) != baseLang(lang) {
+				break
+			}
+			if n, ok := countryNames[tag][code]; ok {
+				name = n
+				break
+			}
+		}
+		if len(names) == 0 || names[0] != name {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return code
+	}
+	return strings.Join(names, " / ")
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPostalLines(t *testing.T) {
+	tests := []struct {
+		name  string
+		party Party
+		want  []string
+	}{
+		{"US", Party{City: "Austin", Region: "TX", PostalCode: "78701", Country: "US"}, []string{"Austin, TX 78701"}},
+		{"US without region", Party{City: "Austin", PostalCode: "78701", Country: "US"}, []string{"Austin, 78701"}},
+		{"CA city only", Party{City: "Toronto", Country: "CA"}, []string{"Toronto"}},
+		{"GB", Party{City: "London", PostalCode: "SW1A 1AA", Country: "GB"}, []string{"London", "SW1A 1AA"}},
+		{"IE with county", Party{City: "Cork", Region: "Co. Cork", PostalCode: "T12 X70A", Country: "IE"}, []string{"Cork", "Co. Cork", "T12 X70A"}},
+		{"PL", Party{City: "Warszawa", PostalCode: "00-950", Country: "PL"}, []string{"00-950 Warszawa"}},
+		{"default with region", Party{City: "Milano", Region: "MI", PostalCode: "20121", Country: "IT"}, []string{"20121 Milano", "MI"}},
+		{"no country", Party{City: "Berlin", PostalCode: "10115"}, []string{"10115 Berlin"}},
+		{"empty", Party{Country: "DE"}, []string{}},
+	}
+	for _, tt := range tests {
+		got := postalLines(tt.party)
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("%s: postalLines = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCountryName(t *testing.T) {
+	saved := invoiceLangs
+	defer func() { invoiceLangs = saved }()
+	tests := []struct {
+		langs []string
+		code  string
+		want  string
+	}{
+		{[]string{"en"}, "DE", "Germany"},
+		{[]string{"en-GB"}, "DE", "Germany"},
+		{[]string{"pl"}, "DE", "Niemcy"},
+		{[]string{"pl-PL"}, "GB", "Wielka Brytania"},
+		{[]string{"ar"}, "SA", "السعودية"},
+		{[]string{"de"}, "PL", "PL"},
+		{[]string{"en"}, "XK", "XK"},
+		{[]string{"pl", "en"}, "DE", "Niemcy / Germany"},
+		{[]string{"en", "pl"}, "AT", "Austria"},
+		{[]string{"de", "en"}, "PL", "PL / Poland"},
+	}
+	for _, tt := range tests {
+		invoiceLangs = tt.langs
+		if got := countryName(tt.code); got != tt.want {
+			t.Errorf("%v: countryName(%s) = %q, want %q", tt.langs, tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestPartyLines(t *testing.T) {
+	savedLangs, savedStrings := invoiceLangs, langStrings
+	defer func() { invoiceLangs, langStrings = savedLangs, savedStrings }()
+	invoiceLangs = []string{"en"}
+	langStrings = LangStrings{VatId: "VAT ID", RegistrationNo: "Registration no."}
+	p := Party{
+		Name:               "Acme Inc.",
+		Address:            []string{"1 Main St"},
+		City:               "Austin",
+		Region:             "TX",
+		PostalCode:         "78701",
+		Country:            "US",
+		TaxIds:             []TaxId{{Type: "EIN", Value: "12-3456789"}},
+		RegistrationNumber: "123",
+		Email:              "billing@acme.test",
+	}
+	want := []string{"Acme Inc.", "1 Main St", "Austin, TX 78701", "United States", "VAT ID: US123", "EIN: 12-3456789", "Registration no.: 123", "billing@acme.test"}
+	if got := partyLines(p, "US123"); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("partyLines = %q, want %q", got, want)
+	}
+}
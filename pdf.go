@@ -11,22 +11,6 @@ import (
 )
 
 const (
-	pageWidth            = 595.28
-	quantityColumnOffset = 260
-	rateColumnOffset     = 290 //unit net
-	amountColumnOffset   = 360 //total net
-	taxColumnOffset      = 430
-	grossColumnOffset    = 480
-	sellerBuyerColSplit   = 290.0
-	leftColWidth          = sellerBuyerColSplit - 40 - 20   // 230
-	rightColWidth         = pageWidth - 40 - sellerBuyerColSplit - 20
-)
-
-const (
-	bodyFontSize   = 9
-	bodyLineHeight = 15 // same as spacing between invoice date lines (issue, sale, due)
-	smallGap         = 10 // small whitespace between title/number, due date/divider
-	itemsToNotesGap  = 52 // gap between invoice items and notes/totals section
 	subtotalLabel    = "Total net price"
 	taxLabel      = "Tax"
 	totalLabel    = "Total gross price"
@@ -39,99 +23,96 @@ func writeLogo(pdf *gopdf.GoPdf, logo string, logoScale float64) {
 	width, height := getImageDimension(logo)
 	scaledWidth := logoScale
 	scaledHeight := float64(height) * scaledWidth / float64(width)
-	x := pageWidth - 40 - scaledWidth
-	_ = pdf.Image(logo, x, 40, &gopdf.Rect{W: scaledWidth, H: scaledHeight})
-	pdf.SetXY(40, 40)
+	x := layout.Right() - scaledWidth
+	_ = pdf.Image(logo, mirrorX(x, scaledWidth), layout.Margin, &gopdf.Rect{W: scaledWidth, H: scaledHeight})
+	pdf.SetXY(layout.Margin, layout.Margin)
 }
 
 func writeHeaderBlock(pdf *gopdf.GoPdf, title, id, issueDate, saleDate, dueDate, billingPeriod string) {
 	if saleDate == "" {
 		saleDate = issueDate
 	}
-	_ = pdf.SetFont("Inter-Bold", "", 24)
-	pdf.SetTextColor(0, 0, 0)
+	setFont(pdf, fontBold, theme.FontSizes.Title)
+	setTextColor(pdf, theme.Colors.Accent)
 	// If user provided a title in JSON/YAML/CLI, use it.
 	// Otherwise, fall back to the language file value.
 	headerTitle := title
 	if headerTitle == "" {
 		headerTitle = langStrings.Title
 	}
-	_ = pdf.Cell(nil, headerTitle)
-	pdf.SetX(40)
-	pdf.Br(38)
-	pdf.SetX(40)
-	_ = pdf.SetFont("Inter", "", bodyFontSize)
-	pdf.SetTextColor(100, 100, 100)
-	_ = pdf.Cell(nil, langStrings.InvNo+" ")
-	_ = pdf.Cell(nil, id)
-	pdf.Br(32)
-	_ = pdf.Cell(nil, langStrings.IssueDate+": ")
-	pdf.SetTextColor(0, 0, 0)
-	_ = pdf.Cell(nil, issueDate)
-	pdf.SetTextColor(100, 100, 100)
-	pdf.Br(bodyLineHeight)
-	_ = pdf.Cell(nil, langStrings.SaleDate+": ")
-	pdf.SetTextColor(0, 0, 0)
-	_ = pdf.Cell(nil, saleDate)
-	pdf.SetTextColor(100, 100, 100)
-	pdf.Br(bodyLineHeight)
-	_ = pdf.Cell(nil, langStrings.DueDate+": ")
-	pdf.SetTextColor(0, 0, 0)
-	_ = pdf.Cell(nil, dueDate)
+	writeText(pdf, headerTitle)
+	pdf.SetX(layout.Margin)
+	pdf.Br(theme.Gaps.Title)
+	pdf.SetX(layout.Margin)
+	setFont(pdf, fontRegular, theme.FontSizes.Body)
+	setTextColor(pdf, theme.Colors.Muted)
+	writeText(pdf, langStrings.InvNo+" ")
+	writeText(pdf, id)
+	pdf.Br(theme.Gaps.Number)
+	writeText(pdf, langStrings.IssueDate+": ")
+	setTextColor(pdf, theme.Colors.Text)
+	writeText(pdf, formatDate(issueDate))
+	setTextColor(pdf, theme.Colors.Muted)
+	pdf.Br(theme.LineHeights.Body)
+	writeText(pdf, langStrings.SaleDate+": ")
+	setTextColor(pdf, theme.Colors.Text)
+	writeText(pdf, formatDate(saleDate))
+	setTextColor(pdf, theme.Colors.Muted)
+	pdf.Br(theme.LineHeights.Body)
+	writeText(pdf, langStrings.DueDate+": ")
+	setTextColor(pdf, theme.Colors.Text)
+	writeText(pdf, formatDate(dueDate))
 	if billingPeriod != "" {
-		pdf.SetTextColor(100, 100, 100)
-		pdf.Br(bodyLineHeight)
-		_ = pdf.Cell(nil, langStrings.BillingPeriod+": ")
-		pdf.SetTextColor(0, 0, 0)
-		_ = pdf.Cell(nil, billingPeriod)
-	}
-	pdf.Br(38)
-	pdf.SetStrokeColor(225, 225, 225)
+		setTextColor(pdf, theme.Colors.Muted)
+		pdf.Br(theme.LineHeights.Body)
+		writeText(pdf, langStrings.BillingPeriod+": ")
+		setTextColor(pdf, theme.Colors.Text)
+		writeText(pdf, billingPeriod)
+	}
+	pdf.Br(theme.Gaps.Header)
 	writeDivider(pdf)
-	pdf.Br(36)
+	pdf.Br(theme.Gaps.Parties)
 }
 
-func writeSellerBuyerColumns(pdf *gopdf.GoPdf, from, to string) {
+func writeSellerBuyerColumns(pdf *gopdf.GoPdf, from, to Party, fromVatId, toVatId string) {
 	startY := pdf.GetY()
-	leftX := 40.0
-	rightX := sellerBuyerColSplit
+	leftX := layout.Margin
+	rightX := layout.SellerBuyerSplit
 
-	// Left column: seller — Cell + Br(bodyLineHeight) per line so spacing matches date lines (16pt)
+	// Left column: seller — Cell + Br(theme.LineHeights.Body) per line so spacing matches date lines (16pt)
 	pdf.SetX(leftX)
-	pdf.SetTextColor(75, 75, 75)
-	_ = pdf.SetFont("Inter", "", bodyFontSize)
-	_ = pdf.Cell(nil, langStrings.Seller)
-	pdf.Br(24)
-	pdf.SetTextColor(55, 55, 55)
-	formattedFrom := strings.ReplaceAll(from, `\n`, "\n")
-	fromLines := strings.Split(formattedFrom, "\n")
+	setTextColor(pdf, theme.Colors.Label)
+	setFont(pdf, fontRegular, theme.FontSizes.Body)
+	writeText(pdf, langStrings.Seller)
+	pdf.Br(theme.Gaps.SectionHeading)
+	setTextColor(pdf, theme.Colors.Secondary)
+	fromLines := partyLines(from, fromVatId)
 	for i := 0; i < len(fromLines); i++ {
 		pdf.SetX(leftX)
-		_ = pdf.SetFont("Inter", "", bodyFontSize)
-		_ = pdf.Cell(nil, fromLines[i])
-		pdf.Br(bodyLineHeight)
+		setFont(pdf, fontRegular, theme.FontSizes.Body)
+		writeText(pdf, fromLines[i])
+		pdf.Br(theme.LineHeights.Body)
 	}
 	leftBottom := pdf.GetY()
 
-	// Right column: buyer — Cell + Br(bodyLineHeight) per line so spacing matches date lines
+	// Right column: buyer — Cell + Br(theme.LineHeights.Body) per line so spacing matches date lines
 	// gopdf Br() resets X to left margin, so SetX(rightX) before each line
 	pdf.SetXY(rightX, startY)
-	pdf.SetTextColor(75, 75, 75)
-	_ = pdf.SetFont("Inter", "", bodyFontSize)
-	_ = pdf.Cell(nil, langStrings.Buyer)
-	pdf.Br(24)
-	formattedTo := strings.ReplaceAll(to, `\n`, "\n")
-	toLines := strings.Split(formattedTo, "\n")
+	setTextColor(pdf, theme.Colors.Label)
+	setFont(pdf, fontRegular, theme.FontSizes.Body)
+	writeText(pdf, langStrings.Buyer)
+	pdf.Br(theme.Gaps.SectionHeading)
+	toLines := partyLines(to, toVatId)
 	for i := 0; i < len(toLines); i++ {
 		pdf.SetX(rightX)
 		if i == 0 {
-			pdf.SetTextColor(0, 0, 0)
+			setTextColor(pdf, theme.Colors.Text)
 		} else {
-			pdf.SetTextColor(55, 55, 55)
+			setTextColor(pdf, theme.Colors.Secondary)
 		}
-		_ = pdf.SetFont("Inter", "", bodyFontSize)
-		_ = pdf.Cell(nil, toLines[i])
-		pdf.Br(bodyLineHeight)
+		setFont(pdf, fontRegular, theme.FontSizes.Body)
+		writeText(pdf, toLines[i])
+		pdf.Br(theme.LineHeights.Body)
 	}
 	rightBottom := pdf.GetY()
 
@@ -140,110 +121,138 @@ func writeSellerBuyerColumns(pdf *gopdf.GoPdf, from, to string) {
 	} else {
 		pdf.SetY(rightBottom)
 	}
-	pdf.SetX(40)
-	pdf.Br(48)
+	pdf.SetX(layout.Margin)
+	pdf.Br(theme.Gaps.Items)
 }
 
 // writeDivider draws a light horizontal divider across the content width at the current Y
 func writeDivider(pdf *gopdf.GoPdf) {
-	pdf.SetStrokeColor(225, 225, 225)
-	pdf.Line(40, pdf.GetY(), pageWidth-40, pdf.GetY())
-	pdf.Br(bodyLineHeight)
+	setDividerStroke(pdf)
+	drawLine(pdf, layout.Margin, pdf.GetY(), layout.Right(), pdf.GetY())
+	pdf.Br(theme.LineHeights.Body)
 }
 
 // writeNarrowDivider draws a shorter divider used in the totals section
 func writeNarrowDivider(pdf *gopdf.GoPdf) {
-	pdf.SetStrokeColor(225, 225, 225)
+	setDividerStroke(pdf)
 	y := pdf.GetY()
-	pdf.Line(amountColumnOffset, y, pageWidth-40, y)
+	drawLine(pdf, layout.AmountColumn, y, layout.Right(), y)
 	pdf.Br(10)
 }
 
 func writeHeaderRow(pdf *gopdf.GoPdf) {
-	_ = pdf.SetFont("Inter", "", bodyFontSize - 1)
-	pdf.SetTextColor(55, 55, 55)
-	_ = pdf.Cell(nil, strings.ToUpper(langStrings.Item))
-	pdf.SetX(quantityColumnOffset)
-	_ = pdf.Cell(nil, strings.ToUpper(langStrings.Qty))
-	pdf.SetX(rateColumnOffset)
-	_ = pdf.Cell(nil, strings.ToUpper(langStrings.UnitNet))
-	pdf.SetX(amountColumnOffset)
-	_ = pdf.Cell(nil, strings.ToUpper(langStrings.TotalNet))
-
-	baseTaxHeader := langStrings.Tax
-	if file.TaxName != "" {
-		baseTaxHeader = file.TaxName
+	setFont(pdf, fontRegular, theme.FontSizes.ColumnHeader)
+	setTextColor(pdf, theme.Colors.Secondary)
+
+	baseTaxHeader := taxDisplayName("")
+	if len(file.Taxes) == 1 {
+		baseTaxHeader = taxDisplayName(file.Taxes[0].Name)
 	}
-	pdf.SetX(taxColumnOffset)
-	_ = pdf.Cell(nil, strings.ToUpper(baseTaxHeader))
-	pdf.SetX(grossColumnOffset)
-	_ = pdf.Cell(nil, strings.ToUpper(langStrings.TotalGross))
-	pdf.Br(24)
+	writeColumnHeaders(pdf, []columnHeader{
+		{layout.Margin, layout.QuantityColumn - 10, langStrings.Item},
+		{layout.QuantityColumn, layout.RateColumn, langStrings.Qty},
+		{layout.RateColumn, layout.AmountColumn, langStrings.UnitNet},
+		{layout.AmountColumn, layout.TaxColumn, langStrings.TotalNet},
+		{layout.TaxColumn, layout.GrossColumn, baseTaxHeader},
+		{layout.GrossColumn, layout.Right(), langStrings.TotalGross},
+	}, theme.Gaps.ColumnHeader)
+}
+
+// columnHeader is the heading of a table column running from x to end.
+type columnHeader struct {
+	x, end float64
+	text   string
+}
+
+// writeColumnHeaders prints a row of upper-case column headings. A heading
+// wider than its column, such as a bilingual one, wraps onto further lines,
+// and the row then moves down by the extra lines before the gap.
+func writeColumnHeaders(pdf *gopdf.GoPdf, headers []columnHeader, gap float64) {
+	y := pdf.GetY()
+	lineHeight := theme.FontSizes.ColumnHeader * 1.25
+	extra := 0.0
+	for _, header := range headers {
+		lines := wrapText(pdf, strings.ToUpper(header.text), header.end-header.x)
+		for i, line := range lines {
+			pdf.SetXY(header.x, y+float64(i)*lineHeight)
+			writeText(pdf, line)
+		}
+		if height := float64(len(lines)-1) * lineHeight; height > extra {
+			extra = height
+		}
+	}
+	pdf.SetY(y + extra)
+	pdf.Br(gap)
 }
 
 func writeNotes(pdf *gopdf.GoPdf, notes, paymentMethod, bank, swift, accountNo string) {
-	_ = pdf.SetFont("Inter", "", bodyFontSize)
-	pdf.SetTextColor(55, 55, 55)
-	_ = pdf.Cell(nil, langStrings.Notes)
-	pdf.Br(24)
-	_ = pdf.SetFont("Inter", "", bodyFontSize)
-	pdf.SetTextColor(0, 0, 0)
+	setFont(pdf, fontRegular, theme.FontSizes.Body)
+	setTextColor(pdf, theme.Colors.Secondary)
+	writeText(pdf, langStrings.Notes)
+	pdf.Br(theme.Gaps.SectionHeading)
+	setFont(pdf, fontRegular, theme.FontSizes.Body)
+	setTextColor(pdf, theme.Colors.Text)
 
 	if paymentMethod != "" || bank != "" || swift != "" || accountNo != "" {
 		if paymentMethod != "" {
-			_ = pdf.Cell(nil, langStrings.Payment+": "+paymentMethod)
-			pdf.Br(bodyLineHeight)
+			writeText(pdf, langStrings.Payment+": "+paymentMethod)
+			pdf.Br(theme.LineHeights.Body)
 		}
 		if bank != "" {
-			_ = pdf.Cell(nil, langStrings.Bank+": "+bank)
-			pdf.Br(bodyLineHeight)
+			writeText(pdf, langStrings.Bank+": "+bank)
+			pdf.Br(theme.LineHeights.Body)
 		}
 		if swift != "" {
-			_ = pdf.Cell(nil, langStrings.Swift+": "+swift)
-			pdf.Br(bodyLineHeight)
+			writeText(pdf, langStrings.Swift+": "+swift)
+			pdf.Br(theme.LineHeights.Body)
 		}
 		if accountNo != "" {
-			_ = pdf.Cell(nil, langStrings.AccountNo+": "+accountNo)
-			pdf.Br(bodyLineHeight)
+			writeText(pdf, langStrings.AccountNo+": "+accountNo)
+			pdf.Br(theme.LineHeights.Body)
 		}
 		if notes != "" {
-			pdf.Br(bodyLineHeight)
+			pdf.Br(theme.LineHeights.Body)
 		}
 	}
 
 	formattedNotes := strings.ReplaceAll(notes, `\n`, "\n")
 	notesLines := strings.Split(formattedNotes, "\n")
 	for i := 0; i < len(notesLines); i++ {
-		_ = pdf.Cell(nil, notesLines[i])
-		pdf.Br(bodyLineHeight)
+		writeText(pdf, notesLines[i])
+		pdf.Br(theme.LineHeights.Body)
 	}
 
 	pdf.Br(48)
 }
+// writeFooter prints the invoice ID and a rule at the bottom of the page, and
+// "Page X of Y" at the right end when the invoice has more than one page.
 func writeFooter(pdf *gopdf.GoPdf, id string) {
-	pdf.SetY(800)
-
-	_ = pdf.SetFont("Inter", "", bodyFontSize)
-	pdf.SetTextColor(55, 55, 55)
-	_ = pdf.Cell(nil, id)
-	pdf.SetStrokeColor(225, 225, 225)
-	pdf.Line(pdf.GetX()+10, pdf.GetY()+6, 550, pdf.GetY()+6)
+	pdf.SetY(layout.FooterY)
+
+	setFont(pdf, fontRegular, theme.FontSizes.Body)
+	setTextColor(pdf, theme.Colors.Secondary)
+	writeText(pdf, id)
+	lineEnd := layout.Right() - 5
+	if pageTotal > 1 {
+		label := pageLabel(pdf.GetNumberOfPages(), pageTotal)
+		width := textWidth(pdf, label)
+		lineEnd = layout.Right() - width - 10
+		lineStart := pdf.GetX() + 10
+		pdf.SetX(layout.Right() - width)
+		writeText(pdf, label)
+		setDividerStroke(pdf)
+		drawLine(pdf, lineStart, pdf.GetY()+6, lineEnd, pdf.GetY()+6)
+		pdf.Br(48)
+		return
+	}
+	setDividerStroke(pdf)
+	drawLine(pdf, pdf.GetX()+10, pdf.GetY()+6, lineEnd, pdf.GetY()+6)
 	pdf.Br(48)
 }
 
-func writeRow(pdf *gopdf.GoPdf, item string, quantity int, rate float64) {
-	_ = pdf.SetFont("Inter", "", bodyFontSize)
-	pdf.SetTextColor(0, 0, 0)
-
-	// net values
-	totalNet := float64(quantity) * rate
-	amountNet := strconv.FormatFloat(totalNet, 'f', 2, 64)
-
-	// wrap item name so it doesn't overlap other columns
-	leftMargin := pdf.MarginLeft()
-	maxItemWidth := float64(quantityColumnOffset) - 10 - leftMargin
-
-	words := strings.Fields(item)
+// wrapText splits text into lines that fit within maxWidth using the current font.
+func wrapText(pdf *gopdf.GoPdf, text string, maxWidth float64) []string {
+	words := strings.Fields(text)
 	var lines []string
 	current := ""
 	for _, w := range words {
@@ -251,8 +260,8 @@ func writeRow(pdf *gopdf.GoPdf, item string, quantity int, rate float64) {
 		if current != "" {
 			candidate = current + " " + w
 		}
-		width, _ := pdf.MeasureTextWidth(candidate)
-		if width <= maxItemWidth || current == "" {
+		width := textWidth(pdf, candidate)
+		if width <= maxWidth || current == "" {
 			current = candidate
 		} else {
 			lines = append(lines, current)
@@ -262,137 +271,405 @@ func writeRow(pdf *gopdf.GoPdf, item string, quantity int, rate float64) {
 	if current != "" {
 		lines = append(lines, current)
 	}
+	return lines
+}
+
+// formatPercent renders a rate such as 0.09975 as "9.975%", with at least two
+// decimals and more only when the rate needs them.
+func formatPercent(rate Decimal) string {
+	percent := rate.Mul(decimalFromInt(100))
+	places := 2
+	for places < 6 && percent.Round(places, RoundHalfUp).Cmp(percent) != 0 {
+		places++
+	}
+	return localizeNumber(percent.Round(places, RoundHalfUp).StringFixed(places)) + "%"
+}
+
+// formatTaxRate renders a tax rate as a percentage followed by the optional tax
+// category, or n/a for an uncategorized zero rate. Under a non-standard tax
+// treatment it renders the treatment's short label instead (e.g. RC).
+func formatTaxRate(rate Decimal, category string) string {
+	switch TaxTreatment(file.TaxTreatment) {
+	case TreatmentReverseCharge:
+		return langStrings.TaxReverseCharge
+	case TreatmentExempt:
+		return langStrings.TaxExempt
+	case TreatmentOutsideScope:
+		return langStrings.TaxOutsideScope
+	}
+	if rate.IsZero() && category == "" {
+		return langStrings.NA
+	}
+	text := formatPercent(rate)
+	if category != "" {
+		text += " " + category
+	}
+	return text
+}
+
+// taxDisplayName returns the printed name of a tax, falling back to the taxName
+// setting and then to the language file.
+func taxDisplayName(name string) string {
+	if name != "" {
+		return name
+	}
+	if file.TaxName != "" {
+		return file.TaxName
+	}
+	return langStrings.Tax
+}
 
-	lineHeight := float64(bodyLineHeight)
+// formatQuantity renders a quantity with the given number of decimals, or
+// with as many as needed when precision is negative.
+func formatQuantity(quantity float64, precision int) string {
+	if precision < 0 {
+		return localizeNumber(strconv.FormatFloat(quantity, 'f', -1, 64))
+	}
+	return localizeNumber(strconv.FormatFloat(quantity, 'f', precision, 64))
+}
+
+// formatMoney renders an amount with two decimals using the invoice rounding
+// mode and the locale's separators.
+func formatMoney(amount Decimal) string {
+	mode, _ := parseRoundingMode(file.Rounding)
+	return localizeNumber(amount.Round(moneyPlaces, mode).StringFixed(moneyPlaces))
+}
+
+func writeRow(pdf *gopdf.GoPdf, line LineTotals) {
+	item := line.Item
+	setFont(pdf, fontRegular, theme.FontSizes.Body)
+	setTextColor(pdf, theme.Colors.Text)
+
+	// wrap item name so it doesn't overlap other columns
+	leftMargin := pdf.MarginLeft()
+	maxItemWidth := layout.QuantityColumn - 10 - leftMargin
+	lines := wrapText(pdf, item.Description, maxItemWidth)
+
+	lineHeight := theme.LineHeights.Body
 
 	// print first line with quantities/rate/amount
 	pdf.SetX(leftMargin)
 	if len(lines) > 0 {
-		_ = pdf.Cell(nil, lines[0])
+		writeText(pdf, lines[0])
 	} else {
-		_ = pdf.Cell(nil, item)
+		writeText(pdf, item.Description)
 	}
-	pdf.SetX(quantityColumnOffset)
-	_ = pdf.Cell(nil, strconv.Itoa(quantity))
-	pdf.SetX(rateColumnOffset)
-	_ = pdf.Cell(nil, currencySymbols[file.Currency]+strconv.FormatFloat(rate, 'f', 2, 64))
-	pdf.SetX(amountColumnOffset)
-	_ = pdf.Cell(nil, currencySymbols[file.Currency]+amountNet)
-
-	// tax rate per item (uses global tax rate) – just the value, header label is in writeHeaderRow
-	pdf.SetX(taxColumnOffset)
-	taxRateText := langStrings.NA
-	if file.Tax != 0 {
-		taxRateText = strconv.FormatFloat(file.Tax*100, 'f', 2, 64) + "%"
+	pdf.SetX(layout.QuantityColumn)
+	quantityText := formatQuantity(item.Quantity, file.QuantityPrecision)
+	if item.Unit != "" {
+		quantityText += " " + item.Unit
+	}
+	writeText(pdf, quantityText)
+	// a discount before tax (including the line's share of the document
+	// discount) lowers the unit and total net prices; the undiscounted ones
+	// are shown struck through on the next line
+	discountOrder, _ := parseDiscountOrder(file.DiscountOrder)
+	discounted := !line.Discount.IsZero()
+	unitPrice := decimalFromFloat(item.UnitPrice)
+	if discounted && discountOrder == DiscountBeforeTax {
+		unitPrice = line.Net.Quo(decimalFromFloat(item.Quantity))
 	}
-	_ = pdf.Cell(nil, taxRateText)
+	pdf.SetX(layout.RateColumn)
+	writeText(pdf, formatCurrency(unitPrice))
+	pdf.SetX(layout.AmountColumn)
+	writeText(pdf, formatCurrency(line.Net))
+
+	// tax rate of this item – just the value, header label is in writeHeaderRow;
+	// stacked taxes show their combined rate, cut to 3 decimals to fit the column
+	pdf.SetX(layout.TaxColumn)
+	writeText(pdf, formatTaxRate(line.TaxRate.Round(5, RoundHalfUp), item.TaxCategory))
 
 	// total gross per item (net + tax amount) – header label is in writeHeaderRow
-	pdf.SetX(grossColumnOffset)
-	totalTaxForItem := totalNet * file.Tax
-	totalGross := totalNet + totalTaxForItem
-	_ = pdf.Cell(nil, currencySymbols[file.Currency]+strconv.FormatFloat(totalGross, 'f', 2, 64))
+	pdf.SetX(layout.GrossColumn)
+	writeText(pdf, formatCurrency(line.Gross))
 
 	pdf.Br(lineHeight)
 
+	// undiscounted prices go on the next line, next to whatever follows the
+	// item name there: the net prices before tax, the gross price after tax
+	if discounted {
+		setTextColor(pdf, theme.Colors.Muted)
+		if discountOrder == DiscountBeforeTax {
+			writeStruck(pdf, layout.RateColumn, formatCurrency(decimalFromFloat(item.UnitPrice)))
+			writeStruck(pdf, layout.AmountColumn, formatCurrency(line.Original))
+		} else {
+			writeStruck(pdf, layout.GrossColumn, formatCurrency(line.Original))
+		}
+		setTextColor(pdf, theme.Colors.Text)
+		if len(lines) < 2 && item.SKU == "" && strings.TrimSpace(item.Notes) == "" {
+			pdf.Br(lineHeight)
+		}
+	}
+
 	// print any wrapped continuation lines for the item name (no quantities/rates on these)
 	for i := 1; i < len(lines); i++ {
 		pdf.SetX(leftMargin)
-		_ = pdf.Cell(nil, lines[i])
+		writeText(pdf, lines[i])
 		pdf.Br(lineHeight)
 	}
 
+	// SKU and per-line notes (in italics) go below the name in gray, wrapped to
+	// the same width
+	setTextColor(pdf, theme.Colors.Muted)
+	if item.SKU != "" {
+		pdf.SetX(leftMargin)
+		writeText(pdf, item.SKU)
+		pdf.Br(lineHeight)
+	}
+	setFont(pdf, fontItalic, theme.FontSizes.Body)
+	formattedNotes := strings.ReplaceAll(item.Notes, `\n`, "\n")
+	for _, paragraph := range strings.Split(formattedNotes, "\n") {
+		for _, line := range wrapText(pdf, paragraph, maxItemWidth) {
+			pdf.SetX(leftMargin)
+			writeText(pdf, line)
+			pdf.Br(lineHeight)
+		}
+	}
+	setFont(pdf, fontRegular, theme.FontSizes.Body)
+
 	// bottom padding between items so rows stay visually separated,
 	// regardless of how many wrapped lines the item name used
-	pdf.Br(10)
+	pdf.Br(theme.Gaps.Row)
 }
 
-func writeTotals(pdf *gopdf.GoPdf, startY float64, subtotal float64, tax float64, discount float64) {
+// writeStruck prints text at x on the current line with a line through it,
+// keeping the current Y so other cells can share the line.
+func writeStruck(pdf *gopdf.GoPdf, x float64, text string) {
+	y := pdf.GetY()
+	pdf.SetX(x)
+	writeText(pdf, text)
+	width := textWidth(pdf, text)
+	pdf.SetStrokeColor(theme.Colors.Muted.R, theme.Colors.Muted.G, theme.Colors.Muted.B)
+	pdf.SetLineWidth(1)
+	pdf.SetLineType("solid")
+	drawLine(pdf, x, y+5, x+width, y+5)
+	pdf.SetY(y)
+}
+
+func writeTotals(pdf *gopdf.GoPdf, startY float64, totals Totals) {
 	pdf.SetY(startY)
-	writeTotalWithCode(pdf, langStrings.TotalNetPrice, subtotal, false)
+	writeTotalWithCode(pdf, langStrings.TotalNetPrice, totals.Net, false)
 
-	// Tax lines: one for rate, one for amount
-	baseTaxLabel := langStrings.Tax
-	if file.TaxName != "" {
-		baseTaxLabel = file.TaxName
+	// A discount before tax comes right after the net price it lowers, one
+	// after tax follows the tax lines it is taken off with.
+	discountOrder, _ := parseDiscountOrder(file.DiscountOrder)
+	if discountOrder == DiscountBeforeTax && totals.Discount.Sign() > 0 {
+		writeTotalWithCode(pdf, langStrings.Discount, totals.Discount, false)
 	}
-	// Tax rate (percentage or n/a)
-	rateWord := langStrings.Rate
-	taxRateLabel := baseTaxLabel + " " + rateWord
-	naText := langStrings.NA
-	taxRateValue := naText
-	if file.Tax != 0 {
-		taxRateValue = strconv.FormatFloat(file.Tax*100, 'f', 2, 64) + "%"
+
+	// Tax breakdown grouped by tax and rate, then one amount line per tax
+	writeTaxSummary(pdf, totals.TaxGroups)
+	for _, tax := range totals.TaxTotals {
+		// a zero line is only worth printing when it is the only tax
+		if tax.Amount.IsZero() && len(totals.TaxTotals) > 1 {
+			continue
+		}
+		writeTotalWithCode(pdf, taxDisplayName(tax.Name)+" "+langStrings.Amount, tax.Amount, false)
 	}
-	writeTotalRaw(pdf, taxRateLabel, taxRateValue)
-	// Tax amount (always shown, even if 0)
-	amountWord := langStrings.Amount
-	taxAmountLabel := baseTaxLabel + " " + amountWord
-	writeTotalWithCode(pdf, taxAmountLabel, tax, false)
 
-	if discount > 0 {
-		writeTotalWithCode(pdf, langStrings.Discount, discount, false)
+	if discountOrder == DiscountAfterTax && totals.Discount.Sign() > 0 {
+		writeTotalWithCode(pdf, langStrings.Discount, totals.Discount, false)
 	}
 	// Total gross price (net + tax − discount)
-	totalGross := subtotal + tax - discount
-	writeTotalWithCode(pdf, langStrings.TotalGrossPrice, totalGross, false)
+	writeTotalWithCode(pdf, langStrings.TotalGrossPrice, totals.Gross, false)
+
+	// Withholding tax (only if set), shown as a deduction
+	if !totals.Withholding.IsZero() {
+		writeTotalWithCode(pdf, withholdingLabel(), totals.Withholding.Neg(), false)
+	}
 
 	// Paid (only if non-zero)
-	if file.Paid != 0 {
-		writeTotalWithCode(pdf, langStrings.PaidLabel, file.Paid, false)
+	if !totals.Paid.IsZero() {
+		writeTotalWithCode(pdf, langStrings.PaidLabel, totals.Paid, false)
+	}
+
+	// Total due (always shown): total gross − withholding − paid
+	writeNarrowDivider(pdf)
+	writeTotalWithCode(pdf, langStrings.TotalDue, totals.Due, true)
+	if file.AmountInWords {
+		writeAmountInWords(pdf, totals.Due)
+	}
+
+	writeTaxTreatmentNote(pdf)
+}
+
+// withholdingLabel is the totals label of the withholding tax line.
+func withholdingLabel() string {
+	label := langStrings.Withholding
+	if file.WithholdingName != "" {
+		label = file.WithholdingName
+	}
+	return label + " " + formatPercent(decimalFromFloat(file.Withholding))
+}
+
+// fitTotalsLabels moves the labels of the totals section left of their usual
+// place when the widest of them, e.g. a bilingual one, would otherwise run
+// into the amounts. They never start left of the quantity column.
+func fitTotalsLabels(pdf *gopdf.GoPdf, totals Totals) {
+	labels := []string{langStrings.TotalNetPrice, langStrings.Discount, langStrings.TotalGrossPrice, langStrings.PaidLabel}
+	for _, tax := range totals.TaxTotals {
+		labels = append(labels, taxDisplayName(tax.Name)+" "+langStrings.Amount)
+	}
+	if !totals.Withholding.IsZero() {
+		labels = append(labels, withholdingLabel())
+	}
+	setFont(pdf, fontRegular, theme.FontSizes.Body)
+	widest := 0.0
+	for _, label := range labels {
+		if width := textWidth(pdf, label); width > widest {
+			widest = width
+		}
+	}
+	setFont(pdf, fontBold, theme.FontSizes.Body)
+	if width := textWidth(pdf, langStrings.TotalDue); width > widest {
+		widest = width
+	}
+
+	x := layout.AmountColumn + 18
+	if fit := layout.GrossColumn - 10 - widest; fit < x {
+		x = fit
+	}
+	if x < layout.QuantityColumn {
+		x = layout.QuantityColumn
+	}
+	layout.TotalsLabel = x
+}
+
+// writeAmountInWords prints the total due spelled out below it, wrapped to the
+// width of the totals.
+func writeAmountInWords(pdf *gopdf.GoPdf, due Decimal) {
+	words, err := amountInWords(due)
+	if err != nil {
+		return
+	}
+	setFont(pdf, fontRegular, theme.FontSizes.Body)
+	setTextColor(pdf, theme.Colors.Label)
+	x := layout.TotalsLabelX()
+	for _, line := range wrapText(pdf, langStrings.AmountInWords+": "+words, layout.Right()-x) {
+		pdf.SetX(x)
+		writeText(pdf, line)
+		pdf.Br(theme.LineHeights.Body)
 	}
+}
 
-	// Total due (always shown): total gross − paid
-	totalDue := totalGross - file.Paid
+// writeTaxTreatmentNote prints the legal mention required by a non-standard
+// tax treatment (e.g. reverse charge) below the totals, wrapped to their width.
+func writeTaxTreatmentNote(pdf *gopdf.GoPdf) {
+	note := file.TaxTreatmentNote
+	if note == "" {
+		switch TaxTreatment(file.TaxTreatment) {
+		case TreatmentReverseCharge:
+			note = langStrings.ReverseChargeNote
+		case TreatmentExempt:
+			note = langStrings.ExemptNote
+		case TreatmentOutsideScope:
+			note = langStrings.OutsideScopeNote
+		default:
+			return
+		}
+	}
+	pdf.Br(10)
+	setFont(pdf, fontItalic, theme.FontSizes.Body)
+	setTextColor(pdf, theme.Colors.Label)
+	x := layout.TotalsLabelX()
+	for _, line := range wrapText(pdf, note, layout.Right()-x) {
+		pdf.SetX(x)
+		writeText(pdf, line)
+		pdf.Br(theme.LineHeights.Body)
+	}
+}
+
+// writeTaxSummary prints the net, tax and gross amounts per tax and rate, using
+// the item table columns so the figures line up with the rows above. When more
+// than one tax is involved, each rate is prefixed with the tax name.
+func writeTaxSummary(pdf *gopdf.GoPdf, groups []TaxGroup) {
+	named := false
+	for _, group := range groups {
+		if group.Name != groups[0].Name {
+			named = true
+		}
+	}
+	headerLabel := taxDisplayName("")
+	if len(groups) > 0 && !named {
+		headerLabel = taxDisplayName(groups[0].Name)
+	}
+
+	setFont(pdf, fontRegular, theme.FontSizes.ColumnHeader)
+	setTextColor(pdf, theme.Colors.Label)
+	writeColumnHeaders(pdf, []columnHeader{
+		{layout.RateColumn, layout.AmountColumn, headerLabel + " " + langStrings.Rate},
+		{layout.AmountColumn, layout.TaxColumn, langStrings.TotalNet},
+		{layout.TaxColumn, layout.GrossColumn, headerLabel},
+		{layout.GrossColumn, layout.Right(), langStrings.TotalGross},
+	}, theme.LineHeights.Body)
+
+	setFont(pdf, fontRegular, theme.FontSizes.Body)
+	setTextColor(pdf, theme.Colors.Text)
+	for _, group := range groups {
+		rateText := formatTaxRate(group.Rate, group.Category)
+		if named {
+			rateText = taxDisplayName(group.Name) + " " + rateText
+		}
+		pdf.SetX(layout.RateColumn)
+		writeText(pdf, rateText)
+		pdf.SetX(layout.AmountColumn)
+		writeText(pdf, formatCurrency(group.Net))
+		pdf.SetX(layout.TaxColumn)
+		writeText(pdf, formatCurrency(group.Tax))
+		pdf.SetX(layout.GrossColumn)
+		writeText(pdf, formatCurrency(group.Gross))
+		pdf.Br(theme.LineHeights.Body)
+	}
 	writeNarrowDivider(pdf)
-	writeTotalWithCode(pdf, langStrings.TotalDue, totalDue, true)
 }
 
-func writeTotal(pdf *gopdf.GoPdf, label string, total float64) {
-	_ = pdf.SetFont("Inter", "", bodyFontSize)
-	pdf.SetTextColor(75, 75, 75)
-	pdf.SetX(amountColumnOffset + 18)
-	_ = pdf.Cell(nil, label)
-	pdf.SetTextColor(0, 0, 0)
-	pdf.SetX(grossColumnOffset)
+func writeTotal(pdf *gopdf.GoPdf, label string, total Decimal) {
+	setFont(pdf, fontRegular, theme.FontSizes.Body)
+	setTextColor(pdf, theme.Colors.Label)
+	pdf.SetX(layout.TotalsLabelX())
+	writeText(pdf, label)
+	setTextColor(pdf, theme.Colors.Text)
+	pdf.SetX(layout.GrossColumn)
 	if label == totalLabel {
-		_ = pdf.SetFont("Inter-Bold", "", bodyFontSize)
+		setFont(pdf, fontBold, theme.FontSizes.Body)
 	} else {
-		_ = pdf.SetFont("Inter", "", bodyFontSize)
+		setFont(pdf, fontRegular, theme.FontSizes.Body)
 	}
-	_ = pdf.Cell(nil, currencySymbols[file.Currency]+strconv.FormatFloat(total, 'f', 2, 64))
-	pdf.Br(20)
+	writeText(pdf, formatCurrency(total))
+	pdf.Br(theme.LineHeights.Totals)
 }
 
-// writeTotalWithCode formats totals with currency code (e.g. "123.45 USD") instead of symbol, used
-// for the final summary lines: total net price, tax amount, total gross price.
-func writeTotalWithCode(pdf *gopdf.GoPdf, label string, total float64, bold bool) {
-	_ = pdf.SetFont("Inter", "", bodyFontSize)
-	pdf.SetTextColor(75, 75, 75)
-	pdf.SetX(amountColumnOffset + 18)
+// writeTotalWithCode writes the final summary lines: total net price, tax amount, total gross price.
+// The currency is placed as the locale does (e.g. "1 234,56 zł"), falling back to its code.
+func writeTotalWithCode(pdf *gopdf.GoPdf, label string, total Decimal, bold bool) {
+	setFont(pdf, fontRegular, theme.FontSizes.Body)
+	setTextColor(pdf, theme.Colors.Label)
+	pdf.SetX(layout.TotalsLabelX())
 	if bold {
-		_ = pdf.SetFont("Inter-Bold", "", bodyFontSize)
+		setFont(pdf, fontBold, theme.FontSizes.Body)
 	} else {
-		_ = pdf.SetFont("Inter", "", bodyFontSize)
-	}
-	_ = pdf.Cell(nil, label)
-	pdf.SetTextColor(0, 0, 0)
-	pdf.SetX(grossColumnOffset)
-	value := strconv.FormatFloat(total, 'f', 2, 64) + " " + file.Currency
-	_ = pdf.Cell(nil, value)
-	pdf.Br(20)
+		setFont(pdf, fontRegular, theme.FontSizes.Body)
+	}
+	writeText(pdf, label)
+	setTextColor(pdf, theme.Colors.Text)
+	if bold {
+		setTextColor(pdf, theme.Colors.Accent)
+	}
+	pdf.SetX(layout.GrossColumn)
+	writeText(pdf, formatCurrency(total))
+	pdf.Br(theme.LineHeights.Totals)
 }
 
 // writeTotalRaw writes a label/value pair without currency formatting (e.g. percentages, text)
 func writeTotalRaw(pdf *gopdf.GoPdf, label string, value string) {
-	_ = pdf.SetFont("Inter", "", bodyFontSize)
-	pdf.SetTextColor(75, 75, 75)
-	pdf.SetX(amountColumnOffset + 18)
-	_ = pdf.Cell(nil, label)
-	pdf.SetTextColor(0, 0, 0)
-	pdf.SetX(grossColumnOffset)
-	_ = pdf.Cell(nil, value)
-	pdf.Br(20)
+	setFont(pdf, fontRegular, theme.FontSizes.Body)
+	setTextColor(pdf, theme.Colors.Label)
+	pdf.SetX(layout.TotalsLabelX())
+	writeText(pdf, label)
+	setTextColor(pdf, theme.Colors.Text)
+	pdf.SetX(layout.GrossColumn)
+	writeText(pdf, value)
+	pdf.Br(theme.LineHeights.Totals)
 }
 
 func getImageDimension(imagePath string) (int, int) {
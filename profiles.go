@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// Profile holds the seller-side settings of one business or trade name. It
+// is stored as profiles/<name>.yaml (or .yml, .json) in the config directory,
+// using the keys of an invoice file.
+type Profile struct {
+	From      Party   `json:"from" yaml:"from"`
+	FromVatId string  `json:"fromVatId" yaml:"fromVatId"`
+	Logo      string  `json:"logo" yaml:"logo"`
+	LogoScale float64 `json:"logoScale" yaml:"logoScale"`
+
+	PaymentMethod string `json:"paymentMethod" yaml:"paymentMethod"`
+	Bank          string `json:"bank" yaml:"bank"`
+	Swift         string `json:"swift" yaml:"swift"`
+	AccountNo     string `json:"accountNo" yaml:"accountNo"`
+
+	// Lang, Currency and Theme are the defaults for the profile's invoices.
+	Lang     string `json:"lang" yaml:"lang"`
+	Currency string `json:"currency" yaml:"currency"`
+	Theme    string `json:"theme" yaml:"theme"`
+
+	// NumberPattern and NumberReset give the profile its own number sequence.
+	NumberPattern string `json:"numberPattern" yaml:"numberPattern"`
+	NumberReset   string `json:"numberReset" yaml:"numberReset"`
+}
+
+// profileName is the --profile flag of generate.
+var profileName string
+
+// profilesDir returns the directory of the seller profiles.
+func profilesDir() (string, error) {
+	dir := configDir()
+	if dir == "" {
+		return "", fmt.Errorf("no config directory on this system for seller profiles")
+	}
+	return filepath.Join(dir, "profiles"), nil
+}
+
+// loadProfile reads a seller profile. Relative logo and theme paths in it are
+// taken relative to the profile file.
+func loadProfile(name string) (Profile, error) {
+	var profile Profile
+	if !idPattern.MatchString(name) {
+		return profile, fmt.Errorf("invalid profile name %q (use lowercase letters, digits, '.', '_' and '-')", name)
+	}
+	dir, err := profilesDir()
+	if err != nil {
+		return profile, err
+	}
+	for _, ext := range []string{".yaml", ".yml", ".json"} {
+		path := filepath.Join(dir, name+ext)
+		data, err := os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return profile, fmt.Errorf("unable to read profile %s: %w", path, err)
+		}
+		if ext == ".json" {
+			dec := json.NewDecoder(bytes.NewReader(data))
+			dec.DisallowUnknownFields()
+			err = dec.Decode(&profile)
+		} else {
+			dec := yaml.NewDecoder(bytes.NewReader(data))
+			dec.KnownFields(true)
+			err = dec.Decode(&profile)
+		}
+		if err != nil {
+			return profile, fmt.Errorf("unable to parse profile %s (only seller-side keys are allowed): %w", path, err)
+		}
+		for _, p := range []*string{&profile.Logo, &profile.Theme} {
+			if *p != "" && !filepath.IsAbs(*p) {
+				*p = filepath.Join(dir, *p)
+			}
+		}
+		return profile, nil
+	}
+	return profile, fmt.Errorf("unknown profile %q (available: %s)", name, strings.Join(profileNames(dir), ", "))
+}
+
+// profileNames lists the profiles in dir, or "none".
+func profileNames(dir string) []string {
+	names := []string{}
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if ext == ".yaml" || ext == ".yml" || ext == ".json" {
+			names = append(names, strings.TrimSuffix(entry.Name(), ext))
+		}
+	}
+	if len(names) == 0 {
+		return []string{"none"}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// applyProfile fills in the seller-side fields of an invoice from a profile.
+// It runs before the client, the imported file and the flags, which all win
+// over it.
+func applyProfile(inv *Invoice, name string, flags *pflag.FlagSet) error {
+	profile, err := loadProfile(name)
+	if err != nil {
+		return err
+	}
+	if profile.From.Name != "" && !flags.Changed("from") {
+		inv.From = profile.From
+	}
+	for _, setting := range []struct {
+		flag  string
+		value string
+		field *string
+	}{
+		{"fromVatId", profile.FromVatId, &inv.FromVatId},
+		{"logo", profile.Logo, &inv.Logo},
+		{"paymentMethod", profile.PaymentMethod, &inv.PaymentMethod},
+		{"bank", profile.Bank, &inv.Bank},
+		{"swift", profile.Swift, &inv.Swift},
+		{"accountNo", profile.AccountNo, &inv.AccountNo},
+		{"lang", profile.Lang, &inv.Lang},
+		{"currency", profile.Currency, &inv.Currency},
+		{"theme", profile.Theme, &inv.Theme},
+		{"numberPattern", profile.NumberPattern, &inv.NumberPattern},
+		{"numberReset", profile.NumberReset, &inv.NumberReset},
+	} {
+		if setting.value != "" && !flags.Changed(setting.flag) {
+			*setting.field = setting.value
+		}
+	}
+	if profile.LogoScale != 0 && !flags.Changed("logoScale") {
+		inv.LogoScale = profile.LogoScale
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/signintech/gopdf"
+)
+
+// pageTotal is the page count of the finished invoice. It is learned from a
+// first rendering pass and is zero while that pass runs.
+var pageTotal int
+
+// renderInvoice lays out the whole invoice. It renders twice: the first pass
+// only counts the pages so that every footer can say "Page X of Y".
+func renderInvoice(totals Totals) (*gopdf.GoPdf, error) {
+	pageTotal = 0
+	rightToLeft = isRTLLanguage(file.Lang)
+	missingGlyphs = map[string]map[rune]bool{}
+	first, err := renderPages(totals)
+	if err != nil {
+		return nil, err
+	}
+	pageTotal = first.GetNumberOfPages()
+	pdf, err := renderPages(totals)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkGlyphs(); err != nil {
+		return nil, err
+	}
+	return pdf, nil
+}
+
+// newDocument starts an empty document in the layout's page size with the invoice fonts loaded.
+func newDocument() (*gopdf.GoPdf, error) {
+	pdf := &gopdf.GoPdf{}
+	pdf.Start(gopdf.Config{
+		PageSize: layout.pageRect(),
+	})
+	pdf.SetMargins(layout.Margin, layout.Margin, layout.Margin, layout.Margin)
+	pdf.AddPage()
+	if err := addFonts(pdf); err != nil {
+		return nil, err
+	}
+	return pdf, nil
+}
+
+// measureHeight returns how far write moves down the page, by running it on a
+// scratch document that is thrown away.
+func measureHeight(scratch *gopdf.GoPdf, write func(pdf *gopdf.GoPdf)) float64 {
+	scratch.SetXY(layout.Margin, 0)
+	write(scratch)
+	return scratch.GetY()
+}
+
+func renderPages(totals Totals) (*gopdf.GoPdf, error) {
+	pdf, err := newDocument()
+	if err != nil {
+		return nil, err
+	}
+	scratch, err := newDocument()
+	if err != nil {
+		return nil, err
+	}
+	fitTotalsLabels(scratch, totals)
+
+	writeLogo(pdf, file.Logo, file.LogoScale)
+	writeHeaderBlock(pdf, file.Title, file.Id, file.Date, file.SaleDate, file.Due, file.BillingPeriod)
+	writeSellerBuyerColumns(pdf, file.From, file.To, file.FromVatId, file.ToVatId)
+	writeHeaderRow(pdf)
+	writeDivider(pdf) // divider before items table
+
+	// When the next row (plus the carried-forward line) doesn't fit, close the
+	// page with the running subtotals and repeat them and the table header on
+	// the next one.
+	var carriedNet, carriedGross Decimal
+	for _, line := range totals.Lines {
+		height := measureHeight(scratch, func(p *gopdf.GoPdf) { writeRow(p, line) })
+		if pdf.GetY()+height+theme.LineHeights.Body > layout.ContentBottom {
+			writeCarriedForward(pdf, langStrings.CarriedForward, carriedNet, carriedGross)
+			newPage(pdf)
+			writeCarriedForward(pdf, langStrings.BroughtForward, carriedNet, carriedGross)
+			writeHeaderRow(pdf)
+			writeDivider(pdf)
+		}
+		writeRow(pdf, line)
+		carriedNet = carriedNet.Add(line.Net)
+		carriedGross = carriedGross.Add(line.Gross)
+	}
+	//writeDivider(pdf) // divider after items table
+	pdf.Br(theme.Gaps.ItemsToNotes)
+
+	// Notes and totals sit side by side and move to a new page together.
+	hasNotes := file.Note != "" || file.PaymentMethod != "" || file.Bank != "" || file.Swift != "" || file.AccountNo != ""
+	writeSection := func(p *gopdf.GoPdf, y float64) {
+		p.SetY(y)
+		if hasNotes {
+			writeNotes(p, file.Note, file.PaymentMethod, file.Bank, file.Swift, file.AccountNo)
+		}
+		notesBottom := p.GetY()
+		writeTotals(p, y, totals)
+		if notesBottom > p.GetY() {
+			p.SetY(notesBottom)
+		}
+	}
+	if pdf.GetY()+measureHeight(scratch, func(p *gopdf.GoPdf) { writeSection(p, 0) }) > layout.ContentBottom {
+		newPage(pdf)
+	}
+	writeSection(pdf, pdf.GetY())
+	writeFooter(pdf, file.Id)
+	return pdf, nil
+}
+
+// newPage closes the current page with its footer and starts the next one.
+func newPage(pdf *gopdf.GoPdf) {
+	writeFooter(pdf, file.Id)
+	pdf.AddPage()
+	pdf.SetXY(layout.Margin, layout.Margin)
+}
+
+// writeCarriedForward prints a subtotal row in the item table columns, used at
+// the bottom of a full page and again at the top of the next one.
+func writeCarriedForward(pdf *gopdf.GoPdf, label string, net, gross Decimal) {
+	setFont(pdf, fontRegular, theme.FontSizes.Body)
+	setTextColor(pdf, theme.Colors.Muted)
+	pdf.SetX(layout.Margin)
+	writeText(pdf, label)
+	pdf.SetX(layout.AmountColumn)
+	writeText(pdf, formatCurrency(net))
+	pdf.SetX(layout.GrossColumn)
+	writeText(pdf, formatCurrency(gross))
+	pdf.Br(theme.Gaps.ColumnHeader)
+}
+
+// pageLabel fills the page number placeholders of the language file's page label.
+func pageLabel(page, pages int) string {
+	return strings.NewReplacer("{page}", strconv.Itoa(page), "{pages}", strconv.Itoa(pages)).Replace(langStrings.Page)
+}
@@ -0,0 +1,81 @@
+package main
+
+import "fmt"
+
+// TaxTreatment says how tax applies to the whole invoice.
+type TaxTreatment string
+
+const (
+	TreatmentStandard      TaxTreatment = "standard"
+	TreatmentReverseCharge TaxTreatment = "reverse-charge"
+	TreatmentExempt        TaxTreatment = "exempt"
+	TreatmentOutsideScope  TaxTreatment = "outside-scope"
+)
+
+// checkTaxTreatment validates the tax treatment of an invoice. Reverse charge
+// needs the VAT IDs of both parties; exempt and outside-scope invoices need
+// none, as small businesses exempt from VAT often have no VAT ID.
+func checkTaxTreatment(inv *Invoice) (TaxTreatment, error) {
+	treatment := TaxTreatment(inv.TaxTreatment)
+	switch treatment {
+	case "":
+		return TreatmentStandard, nil
+	case TreatmentStandard, TreatmentExempt, TreatmentOutsideScope:
+		return treatment, nil
+	case TreatmentReverseCharge:
+		if inv.FromVatId == "" || inv.ToVatId == "" {
+			return "", fmt.Errorf("tax treatment %s requires both fromVatId and toVatId", treatment)
+		}
+		return treatment, nil
+	}
+	return "", fmt.Errorf("unknown tax treatment %q (use %s, %s, %s or %s)", inv.TaxTreatment,
+		TreatmentStandard, TreatmentReverseCharge, TreatmentExempt, TreatmentOutsideScope)
+}
+
+// Tax is one named tax applied to the line items, e.g. GST or a state tax.
+// A compound tax is calculated on the net amount plus every tax listed before
+// it (Quebec QST style); a simple tax is calculated on the net amount only.
+type Tax struct {
+	Name     string  `json:"name" yaml:"name"`
+	Rate     float64 `json:"rate" yaml:"rate"`
+	Compound bool    `json:"compound" yaml:"compound"`
+}
+
+// invoiceTaxes returns the taxes applied to items without their own rate: the
+// taxes list when given, otherwise the single tax/taxName pair.
+func invoiceTaxes(inv *Invoice) []Tax {
+	if len(inv.Taxes) > 0 {
+		return inv.Taxes
+	}
+	return []Tax{{Name: inv.TaxName, Rate: inv.Tax}}
+}
+
+// itemTaxes returns the taxes applied to one item. An item taxRate replaces
+// the invoice tax with a simple tax at that rate (computeTotals rejects it
+// alongside a taxes list, where it would have no name), and a non-standard
+// tax treatment replaces the taxes with a single zero-rated tax.
+func itemTaxes(inv *Invoice, item LineItem) []Tax {
+	if treatment := TaxTreatment(inv.TaxTreatment); treatment != "" && treatment != TreatmentStandard {
+		return []Tax{{Name: inv.TaxName}}
+	}
+	if item.TaxRate != nil {
+		return []Tax{{Name: inv.TaxName, Rate: *item.TaxRate}}
+	}
+	return invoiceTaxes(inv)
+}
+
+// effectiveTaxRate combines stacked taxes into the single rate they amount to
+// on the net price, e.g. 5% GST plus 9.975% compound QST is 15.47375%.
+func effectiveTaxRate(taxes []Tax) Decimal {
+	var effective Decimal
+	one := decimalFromInt(1)
+	for _, tax := range taxes {
+		rate := decimalFromFloat(tax.Rate)
+		if tax.Compound {
+			effective = effective.Add(rate.Mul(one.Add(effective)))
+		} else {
+			effective = effective.Add(rate)
+		}
+	}
+	return effective
+}
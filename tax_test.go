@@ -0,0 +1,63 @@
+package main
+
+import "testing"
+
+func TestCheckTaxTreatment(t *testing.T) {
+	tests := []struct {
+		treatment, fromVatId, toVatId string
+		want                          TaxTreatment
+		wantErr                       bool
+	}{
+		{"", "", "", TreatmentStandard, false},
+		{"standard", "", "", TreatmentStandard, false},
+		{"reverse-charge", "PL5260001246", "DE123456789", TreatmentReverseCharge, false},
+		{"reverse-charge", "PL5260001246", "", "", true},
+		{"reverse-charge", "", "DE123456789", "", true},
+		{"exempt", "", "", TreatmentExempt, false},
+		{"exempt", "PL5260001246", "", TreatmentExempt, false},
+		{"outside-scope", "", "", TreatmentOutsideScope, false},
+		{"zero", "", "", "", true},
+	}
+	for _, tt := range tests {
+		inv := Invoice{TaxTreatment: tt.treatment, FromVatId: tt.fromVatId, ToVatId: tt.toVatId}
+		got, err := checkTaxTreatment(&inv)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("checkTaxTreatment(%q, %q, %q): err = %v, want error %v", tt.treatment, tt.fromVatId, tt.toVatId, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("checkTaxTreatment(%q) = %q, want %q", tt.treatment, got, tt.want)
+		}
+	}
+}
+
+func TestTaxTreatmentTotals(t *testing.T) {
+	tests := []struct {
+		treatment TaxTreatment
+		tax       string
+	}{
+		{TreatmentStandard, "23.00"},
+		{TreatmentReverseCharge, "0.00"},
+		{TreatmentExempt, "0.00"},
+		{TreatmentOutsideScope, "0.00"},
+	}
+	for _, tt := range tests {
+		inv := Invoice{
+			Tax:          0.23,
+			TaxTreatment: string(tt.treatment),
+			FromVatId:    "PL5260001246",
+			ToVatId:      "DE123456789",
+			Items:        []LineItem{{Description: "service", Quantity: 1, UnitPrice: 100}},
+		}
+		totals, err := computeTotals(&inv)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.treatment, err)
+		}
+		if got := totals.Tax.StringFixed(2); got != tt.tax {
+			t.Errorf("%s: tax = %s, want %s", tt.treatment, got, tt.tax)
+		}
+		if got, want := totals.Gross, decimalFromInt(100).Add(totals.Tax); got.Cmp(want) != 0 {
+			t.Errorf("%s: gross = %s, want %s", tt.treatment, got.StringFixed(2), want.StringFixed(2))
+		}
+	}
+}
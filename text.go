@@ -0,0 +1,114 @@
+package main
+
+import (
+	"unicode"
+
+	"github.com/signintech/gopdf"
+)
+
+// All invoice text is printed through setFont, writeText and textWidth rather
+// than gopdf directly. They switch to a fallback font for characters the
+// selected face lacks, and mirror the page for right-to-left languages.
+
+// currentFamily and currentSize are the font last selected with setFont.
+var (
+	currentFamily = fontRegular
+	currentSize   float64
+)
+
+// setFont selects the font family and size of the following text.
+func setFont(pdf *gopdf.GoPdf, family string, size float64) {
+	currentFamily, currentSize = family, size
+	_ = pdf.SetFont(family, "", size)
+}
+
+// textRun is a piece of text printed with a single font.
+type textRun struct {
+	family string
+	text   string
+}
+
+// fontRuns splits text into runs of the current font and, for characters it
+// has no glyph for, the first fallback font that has them. Spaces and
+// punctuation stay in the run they are in.
+func fontRuns(text string) []textRun {
+	primary := fontFamily(currentFamily)
+	var runs []textRun
+	for _, r := range text {
+		family := currentFamily
+		if len(fallbackFonts) > 0 && !primary.covers(r) {
+			for _, f := range fallbackFonts {
+				if f.covers(r) {
+					family = f.family
+					break
+				}
+			}
+		}
+		if n := len(runs); n > 0 && family != runs[n-1].family {
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) && fontFamily(runs[n-1].family).covers(r) {
+				family = runs[n-1].family
+			}
+		}
+		if n := len(runs); n > 0 && runs[n-1].family == family {
+			runs[n-1].text += string(r)
+		} else {
+			runs = append(runs, textRun{family: family, text: string(r)})
+		}
+	}
+	return runs
+}
+
+// writeText prints text at the current position and moves X past it, like
+// pdf.Cell. On right-to-left invoices positions are mirrored: X counts from
+// the right edge of the page and the text extends to the left of it.
+func writeText(pdf *gopdf.GoPdf, text string) {
+	visual := visualText(text, rightToLeft)
+	x := pdf.GetX()
+	width := visualWidth(pdf, visual)
+	if rightToLeft {
+		pdf.SetX(mirrorX(x, width))
+	}
+	y := pdf.GetY()
+	ascent := fontFamily(currentFamily).ascent(currentSize)
+	for _, run := range fontRuns(visual) {
+		_ = pdf.SetFont(run.family, "", currentSize)
+		pdf.SetY(y + ascent - fontFamily(run.family).ascent(currentSize))
+		_ = pdf.Cell(nil, run.text)
+	}
+	_ = pdf.SetFont(currentFamily, "", currentSize)
+	pdf.SetXY(x+width, y)
+}
+
+// textWidth measures text as writeText prints it.
+func textWidth(pdf *gopdf.GoPdf, text string) float64 {
+	return visualWidth(pdf, visualText(text, rightToLeft))
+}
+
+func visualWidth(pdf *gopdf.GoPdf, visual string) float64 {
+	total := 0.0
+	for _, run := range fontRuns(visual) {
+		_ = pdf.SetFont(run.family, "", currentSize)
+		width, _ := pdf.MeasureTextWidth(run.text)
+		total += width
+	}
+	_ = pdf.SetFont(currentFamily, "", currentSize)
+	return total
+}
+
+// drawLine draws a line, mirrored on right-to-left invoices.
+func drawLine(pdf *gopdf.GoPdf, x1, y1, x2, y2 float64) {
+	if rightToLeft {
+		x1, x2 = mirrorX(x1, 0), mirrorX(x2, 0)
+	}
+	pdf.Line(x1, y1, x2, y2)
+}
+
+// mirrorX returns where something width wide that starts at x on a
+// left-to-right page starts on the mirrored page. It is x itself on
+// left-to-right invoices.
+func mirrorX(x, width float64) float64 {
+	if !rightToLeft {
+		return x
+	}
+	return layout.PageWidth - x - width
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/signintech/gopdf"
+	"gopkg.in/yaml.v3"
+)
+
+// Color is an RGB color, written as "#rrggbb" (or "#rgb") in theme files.
+type Color struct {
+	R, G, B uint8
+}
+
+func parseColor(s string) (Color, error) {
+	hex := strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if len(hex) != 6 || err != nil {
+		return Color{}, fmt.Errorf("invalid color %q (use #rrggbb)", s)
+	}
+	return Color{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v)}, nil
+}
+
+func (c *Color) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("invalid color %s (use \"#rrggbb\")", data)
+	}
+	parsed, err := parseColor(s)
+	if err != nil {
+		return err
+	}
+	*c = parsed
+	return nil
+}
+
+func (c *Color) UnmarshalYAML(value *yaml.Node) error {
+	parsed, err := parseColor(value.Value)
+	if err != nil {
+		return err
+	}
+	*c = parsed
+	return nil
+}
+
+// Theme holds the look of the invoice: colors, type sizes and vertical spacing.
+// A theme file only needs the values it changes; the rest come from the
+// built-in default theme.
+type Theme struct {
+	Colors struct {
+		Text      Color `json:"text" yaml:"text"`           // values, item rows, notes
+		Accent    Color `json:"accent" yaml:"accent"`       // title and total due
+		Secondary Color `json:"secondary" yaml:"secondary"` // parties, column headers, footer
+		Label     Color `json:"label" yaml:"label"`         // section and totals labels
+		Muted     Color `json:"muted" yaml:"muted"`         // header labels, SKUs, struck prices
+	} `json:"colors" yaml:"colors"`
+
+	FontSizes struct {
+		Title        float64 `json:"title" yaml:"title"`
+		Body         float64 `json:"body" yaml:"body"`
+		ColumnHeader float64 `json:"columnHeader" yaml:"columnHeader"`
+	} `json:"fontSizes" yaml:"fontSizes"`
+
+	LineHeights struct {
+		Body   float64 `json:"body" yaml:"body"`
+		Totals float64 `json:"totals" yaml:"totals"`
+	} `json:"lineHeights" yaml:"lineHeights"`
+
+	Gaps struct {
+		Title          float64 `json:"title" yaml:"title"`                   // below the title
+		Number         float64 `json:"number" yaml:"number"`                 // below the invoice number
+		Header         float64 `json:"header" yaml:"header"`                 // between the dates and the divider below them
+		Parties        float64 `json:"parties" yaml:"parties"`               // above the seller/buyer columns
+		SectionHeading float64 `json:"sectionHeading" yaml:"sectionHeading"` // below seller, buyer and notes headings
+		Items          float64 `json:"items" yaml:"items"`                   // above the item table
+		ColumnHeader   float64 `json:"columnHeader" yaml:"columnHeader"`     // below the item table header
+		Row            float64 `json:"row" yaml:"row"`                       // between item rows
+		ItemsToNotes   float64 `json:"itemsToNotes" yaml:"itemsToNotes"`     // between the items and notes/totals
+	} `json:"gaps" yaml:"gaps"`
+
+	Divider struct {
+		Color Color   `json:"color" yaml:"color"`
+		Width float64 `json:"width" yaml:"width"`
+		Style string  `json:"style" yaml:"style"` // solid, dashed or dotted
+	} `json:"divider" yaml:"divider"`
+}
+
+// theme is the theme of the invoice being generated.
+var theme = defaultTheme()
+
+// defaultTheme returns the built-in look of the invoice.
+func defaultTheme() Theme {
+	var t Theme
+	t.Colors.Text = Color{0, 0, 0}
+	t.Colors.Accent = Color{0, 0, 0}
+	t.Colors.Secondary = Color{55, 55, 55}
+	t.Colors.Label = Color{75, 75, 75}
+	t.Colors.Muted = Color{100, 100, 100}
+
+	t.FontSizes.Title = 24
+	t.FontSizes.Body = 9
+	t.FontSizes.ColumnHeader = 8
+
+	t.LineHeights.Body = 15 // same as spacing between invoice date lines (issue, sale, due)
+	t.LineHeights.Totals = 20
+
+	t.Gaps.Title = 38
+	t.Gaps.Number = 32
+	t.Gaps.Header = 38
+	t.Gaps.Parties = 36
+	t.Gaps.SectionHeading = 24
+	t.Gaps.Items = 48
+	t.Gaps.ColumnHeader = 24
+	t.Gaps.Row = 10
+	t.Gaps.ItemsToNotes = 52
+
+	t.Divider.Color = Color{225, 225, 225}
+	t.Divider.Width = 1
+	t.Divider.Style = "solid"
+	return t
+}
+
+// loadTheme reads a YAML or JSON theme file over the default theme. An empty
+// path keeps the default theme.
+func loadTheme(path string) error {
+	theme = defaultTheme()
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("unable to read theme file %s: %w", path, err)
+	}
+	if strings.HasSuffix(path, ".json") {
+		err = json.Unmarshal(data, &theme)
+	} else if strings.HasSuffix(path, ".yaml") || strings.HasSuffix(path, ".yml") {
+		err = yaml.Unmarshal(data, &theme)
+	} else {
+		return fmt.Errorf("unsupported theme file type %s (use .json, .yaml or .yml)", path)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to parse theme file %s: %w", path, err)
+	}
+	return validateTheme(&theme, path)
+}
+
+// validateTheme rejects sizes that would make the layout unusable.
+func validateTheme(t *Theme, path string) error {
+	sizes := map[string]float64{
+		"fontSizes.title":        t.FontSizes.Title,
+		"fontSizes.body":         t.FontSizes.Body,
+		"fontSizes.columnHeader": t.FontSizes.ColumnHeader,
+		"lineHeights.body":       t.LineHeights.Body,
+		"lineHeights.totals":     t.LineHeights.Totals,
+	}
+	for name, size := range sizes {
+		if size <= 0 {
+			return fmt.Errorf("theme file %s: %s must be greater than 0", path, name)
+		}
+	}
+	if t.Divider.Width < 0 {
+		return fmt.Errorf("theme file %s: divider.width must not be negative", path)
+	}
+	switch t.Divider.Style {
+	case "solid", "dashed", "dotted":
+	default:
+		return fmt.Errorf("theme file %s: unknown divider.style %q (use solid, dashed or dotted)", path, t.Divider.Style)
+	}
+	return nil
+}
+
+func setTextColor(pdf *gopdf.GoPdf, c Color) {
+	pdf.SetTextColor(c.R, c.G, c.B)
+}
+
+// setDividerStroke prepares the stroke for a divider line.
+func setDividerStroke(pdf *gopdf.GoPdf) {
+	pdf.SetStrokeColor(theme.Divider.Color.R, theme.Divider.Color.G, theme.Divider.Color.B)
+	pdf.SetLineWidth(theme.Divider.Width)
+	pdf.SetLineType(theme.Divider.Style)
+}
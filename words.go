@@ -0,0 +1,213 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// amountInWords spells out an amount for the "amount in words" line: the whole
+// currency units in words followed by the cents as a fraction, e.g. "one
+// hundred twenty-three dollars 45/100". The words come from the number word
+// keys of the invoice language (see numberWords).
+func amountInWords(amount Decimal) (string, error) {
+	words, err := parseNumberWords(langStrings)
+	if err != nil {
+		return "", fmt.Errorf("amount in words is not available for language %s: %w", file.Lang, err)
+	}
+	mode, _ := parseRoundingMode(file.Rounding)
+	fixed := amount.Round(moneyPlaces, mode).StringFixed(moneyPlaces)
+	negative := strings.HasPrefix(fixed, "-")
+	whole, cents, _ := strings.Cut(strings.TrimPrefix(fixed, "-"), ".")
+	n, ok := new(big.Int).SetString(whole, 10)
+	if !ok || !n.IsInt64() {
+		return "", fmt.Errorf("amount %s is too large to write in words", fixed)
+	}
+	spelled, err := words.spell(n.Int64(), file.Currency)
+	if err != nil {
+		return "", err
+	}
+	if negative {
+		spelled = words.minus + " " + spelled
+	}
+	return spelled + " " + cents + "/100", nil
+}
+
+// numberGrammar is how a language puts number words together: the word
+// between tens and units, whether a power of a thousand drops the word for
+// one ("tysiąc", not "jeden tysiąc"), and which form of a noun goes with a
+// number.
+type numberGrammar struct {
+	tensJoin  string
+	bareScale bool
+	form      func(n int64) int
+}
+
+// numberGrammars are the grammars a language pack can name in
+// _numberGrammar. A language whose numbers work like one of them can use it
+// with its own words.
+var numberGrammars = map[string]numberGrammar{
+	// one form for 1, another for everything else
+	"en": {tensJoin: "-", form: englishForm},
+	// forms for 1, for a few (2–4, 22–24, ...) and for many
+	"pl": {tensJoin: " ", bareScale: true, form: polishForm},
+}
+
+// numberWords are the words a language pack gives for spelling out amounts.
+// Lists are comma-separated, and the forms of a noun are separated by "/" in
+// the order of the grammar, e.g. "tysiąc/tysiące/tysięcy":
+//
+//	_numberGrammar   en or pl (see numberGrammars)
+//	_numberWords     0 to 19
+//	_numberTens      20, 30, ... 90
+//	_numberHundreds  100, 200, ... 900
+//	_numberScales    thousand, million, ...
+//	_numberMinus     the word for a negative amount
+//	_currencyWords   CODE: forms, e.g. "USD: dollar/dollars, EUR: euro/euros"
+type numberWords struct {
+	grammar    numberGrammar
+	ones       []string
+	tens       []string
+	hundreds   []string
+	scales     [][]string
+	minus      string
+	currencies map[string][]string
+}
+
+// parseNumberWords reads the number word keys of a language.
+func parseNumberWords(ls LangStrings) (numberWords, error) {
+	var words numberWords
+	if ls.NumberGrammar == "" {
+		return words, fmt.Errorf("its language pack has no number words (_numberGrammar and the other _number keys)")
+	}
+	grammar, ok := numberGrammars[ls.NumberGrammar]
+	if !ok {
+		return words, fmt.Errorf("unknown _numberGrammar %q (use en or pl)", ls.NumberGrammar)
+	}
+	words.grammar = grammar
+	for _, list := range []struct {
+		key   string
+		value string
+		count int
+		words *[]string
+	}{
+		{"_numberWords", ls.NumberWords, 20, &words.ones},
+		{"_numberTens", ls.NumberTens, 8, &words.tens},
+		{"_numberHundreds", ls.NumberHundreds, 9, &words.hundreds},
+	} {
+		*list.words = splitWordList(list.value)
+		if len(*list.words) != list.count {
+			return words, fmt.Errorf("%s needs %d words, has %d", list.key, list.count, len(*list.words))
+		}
+	}
+	for _, scale := range splitWordList(ls.NumberScales) {
+		words.scales = append(words.scales, strings.Split(scale, "/"))
+	}
+	if len(words.scales) == 0 {
+		return words, fmt.Errorf("_numberScales has no words")
+	}
+	if ls.NumberMinus == "" {
+		return words, fmt.Errorf("_numberMinus is empty")
+	}
+	words.minus = ls.NumberMinus
+	words.currencies = map[string][]string{}
+	for _, entry := range splitWordList(ls.CurrencyWords) {
+		code, forms, ok := strings.Cut(entry, ":")
+		if !ok {
+			return words, fmt.Errorf("_currencyWords entry %q is not in CODE: forms form", entry)
+		}
+		words.currencies[strings.ToUpper(strings.TrimSpace(code))] = strings.Split(strings.TrimSpace(forms), "/")
+	}
+	return words, nil
+}
+
+// splitWordList splits a comma-separated list of words.
+func splitWordList(list string) []string {
+	words := []string{}
+	for _, word := range strings.Split(list, ",") {
+		if word = strings.TrimSpace(word); word != "" {
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// pick returns the form of a noun that goes with n. A noun with fewer forms
+// than the grammar uses its last one, e.g. "euro" for every number.
+func (w numberWords) pick(forms []string, n int64) string {
+	i := w.grammar.form(n)
+	if i >= len(forms) {
+		i = len(forms) - 1
+	}
+	return forms[i]
+}
+
+// spell writes a whole number of currency units in words, including the
+// currency name in the right grammatical form. A currency without words is
+// written as its code.
+func (w numberWords) spell(n int64, currency string) (string, error) {
+	name := currency
+	if forms, ok := w.currencies[currency]; ok {
+		name = w.pick(forms, n)
+	}
+	if n == 0 {
+		return w.ones[0] + " " + name, nil
+	}
+	var groups []string
+	for scale, rest := 0, n; rest > 0; scale, rest = scale+1, rest/1000 {
+		group := rest % 1000
+		if group == 0 {
+			continue
+		}
+		if scale == 0 {
+			groups = append(groups, w.belowThousand(int(group)))
+			continue
+		}
+		if scale > len(w.scales) {
+			return "", fmt.Errorf("amount %d is too large to write in words", n)
+		}
+		words := w.pick(w.scales[scale-1], group)
+		if group != 1 || !w.grammar.bareScale {
+			words = w.belowThousand(int(group)) + " " + words
+		}
+		groups = append([]string{words}, groups...)
+	}
+	return strings.Join(groups, " ") + " " + name, nil
+}
+
+// belowThousand writes 1–999 in words.
+func (w numberWords) belowThousand(n int) string {
+	var parts []string
+	if n >= 100 {
+		parts = append(parts, w.hundreds[n/100-1])
+		n %= 100
+	}
+	switch {
+	case n >= 20 && n%10 != 0:
+		parts = append(parts, w.tens[n/10-2]+w.grammar.tensJoin+w.ones[n%10])
+	case n >= 20:
+		parts = append(parts, w.tens[n/10-2])
+	case n > 0:
+		parts = append(parts, w.ones[n])
+	}
+	return strings.Join(parts, " ")
+}
+
+// englishForm picks the noun form that goes with n: one or many.
+func englishForm(n int64) int {
+	if n == 1 {
+		return 0
+	}
+	return 1
+}
+
+// polishForm picks the noun form that goes with n: one, a few or many.
+func polishForm(n int64) int {
+	switch {
+	case n == 1:
+		return 0
+	case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+		return 1
+	}
+	return 2
+}
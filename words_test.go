@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// builtinNumberWords reads the number words of a built-in language pack.
+func builtinNumberWords(t *testing.T, code string) numberWords {
+	t.Helper()
+	data, err := builtinLangs.ReadFile("lang/" + code + ".json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ls LangStrings
+	if err := json.Unmarshal(data, &ls); err != nil {
+		t.Fatal(err)
+	}
+	words, err := parseNumberWords(ls)
+	if err != nil {
+		t.Fatalf("%s: %v", code, err)
+	}
+	return words
+}
+
+func TestSpellNumbers(t *testing.T) {
+	en, pl := builtinNumberWords(t, "en"), builtinNumberWords(t, "pl")
+	tests := []struct {
+		n      int64
+		en, pl string
+	}{
+		{0, "zero dollars", "zero złotych"},
+		{1, "one dollar", "jeden złoty"},
+		{2, "two dollars", "dwa złote"},
+		{5, "five dollars", "pięć złotych"},
+		{12, "twelve dollars", "dwanaście złotych"},
+		{22, "twenty-two dollars", "dwadzieścia dwa złote"},
+		{112, "one hundred twelve dollars", "sto dwanaście złotych"},
+		{1001, "one thousand one dollars", "tysiąc jeden złotych"},
+		{2000, "two thousand dollars", "dwa tysiące złotych"},
+		{5000, "five thousand dollars", "pięć tysięcy złotych"},
+		{1000000, "one million dollars", "milion złotych"},
+		{22000000, "twenty-two million dollars", "dwadzieścia dwa miliony złotych"},
+	}
+	for _, tt := range tests {
+		if got, err := en.spell(tt.n, "USD"); err != nil || got != tt.en {
+			t.Errorf("en spell(%d) = %q, %v, want %q", tt.n, got, err, tt.en)
+		}
+		if got, err := pl.spell(tt.n, "PLN"); err != nil || got != tt.pl {
+			t.Errorf("pl spell(%d) = %q, %v, want %q", tt.n, got, err, tt.pl)
+		}
+	}
+	if got, _ := pl.spell(3, "EUR"); got != "trzy euro" {
+		t.Errorf("pl spell(3, EUR) = %q, want a single-form currency name", got)
+	}
+	if got, _ := en.spell(3, "SEK"); got != "three SEK" {
+		t.Errorf("en spell(3, SEK) = %q, want the code of a currency without words", got)
+	}
+}
+
+func TestParseNumberWords(t *testing.T) {
+	en := LangStrings{
+		NumberGrammar:  "en",
+		NumberWords:    "zero, one, two, three, four, five, six, seven, eight, nine, ten, eleven, twelve, thirteen, fourteen, fifteen, sixteen, seventeen, eighteen, nineteen",
+		NumberTens:     "twenty, thirty, forty, fifty, sixty, seventy, eighty, ninety",
+		NumberHundreds: "one hundred, two hundred, three hundred, four hundred, five hundred, six hundred, seven hundred, eight hundred, nine hundred",
+		NumberScales:   "thousand",
+		NumberMinus:    "minus",
+		CurrencyWords:  "USD: dollar/dollars",
+	}
+	words, err := parseNumberWords(en)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := words.spell(1000000, "USD"); err == nil {
+		t.Error("spell went past the largest scale of the pack")
+	}
+	tests := []struct {
+		name   string
+		change func(*LangStrings)
+	}{
+		{"no grammar", func(ls *LangStrings) { ls.NumberGrammar = "" }},
+		{"unknown grammar", func(ls *LangStrings) { ls.NumberGrammar = "fr" }},
+		{"short word list", func(ls *LangStrings) { ls.NumberWords = "zero, one" }},
+		{"no scales", func(ls *LangStrings) { ls.NumberScales = "" }},
+		{"no minus", func(ls *LangStrings) { ls.NumberMinus = "" }},
+		{"bad currency", func(ls *LangStrings) { ls.CurrencyWords = "dollar" }},
+	}
+	for _, tt := range tests {
+		ls := en
+		tt.change(&ls)
+		if _, err := parseNumberWords(ls); err == nil {
+			t.Errorf("%s: parseNumberWords accepted it", tt.name)
+		}
+	}
+}
+
+func TestAmountInWords(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	savedFile, savedLang := file, langStrings
+	defer func() { file, langStrings = savedFile, savedLang }()
+	tests := []struct {
+		amount, currency, lang, want string
+	}{
+		{"123.45", "USD", "en", "one hundred twenty-three dollars 45/100"},
+		{"123.45", "PLN", "pl", "sto dwadzieścia trzy złote 45/100"},
+		{"1.005", "EUR", "en", "one euro 01/100"},
+		{"-2.50", "PLN", "pl-PL", "minus dwa złote 50/100"},
+		{"1000000", "USD", "en-US", "one million dollars 00/100"},
+	}
+	for _, tt := range tests {
+		file = Invoice{Currency: tt.currency, Lang: tt.lang}
+		if err := loadLang(tt.lang, "", "warn"); err != nil {
+			t.Fatal(err)
+		}
+		got, err := amountInWords(decimalFromString(t, tt.amount))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("amountInWords(%s %s, %s) = %q, want %q", tt.amount, tt.currency, tt.lang, got, tt.want)
+		}
+	}
+
+	// Arabic has no number words, and must not get the English ones
+	file = Invoice{Currency: "USD", Lang: "ar"}
+	if err := loadLang("ar", "", "error"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := amountInWords(decimalFromInt(1)); err == nil || !strings.Contains(err.Error(), "ar") {
+		t.Errorf("amountInWords in ar: err = %v, want an error naming the language", err)
+	}
+
+	// a bilingual invoice spells the amount in its first language
+	file = Invoice{Currency: "PLN", Lang: "pl", Lang2: "en"}
+	if err := loadLang("pl", "en", "error"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := amountInWords(decimalFromInt(2)); got != "dwa złote 00/100" {
+		t.Errorf("amountInWords in pl/en = %q, want Polish words", got)
+	}
+}